@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
@@ -8,17 +10,33 @@ import (
 	"github.com/saas/hostgo/pkg/http"
 	"github.com/saas/hostgo/pkg/ops"
 	"github.com/saas/hostgo/pkg/types"
+	"github.com/saas/hostgo/pkg/units"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 )
 
 const appConfigFileName = "csail.yml"
 
+const (
+	deployStrategyAuto   = "auto"
+	deployStrategyDocker = "docker"
+	deployStrategyBinary = "binary"
+)
+
+// dockerDeployFlags only apply when the app is packaged as a docker image.
+var dockerDeployFlags = []string{"dockerfile", "build-arg", "target", "no-cache", "platform", "tag", "registry", "remote-build"}
+
 func appsCmd() {
 	createCmd := &cobra.Command{
 		Use: "create",
@@ -41,32 +59,177 @@ func appsCmd() {
 	deploymentCmd := &cobra.Command{
 		Use: "deploy",
 		Run: func(cmd *cobra.Command, args []string) {
-			runDockerDeploy()
+			if image, _ := cmd.Flags().GetString("image"); image != "" {
+				deployImage(image)
+				return
+			}
+			if skipChecks, _ := cmd.Flags().GetBool("skip-checks"); !skipChecks {
+				if cfg, err := readAppConfig(); err == nil {
+					runPredeployChecks(cfg.Predeploy)
+				}
+			}
+			strategy, _ := cmd.Flags().GetString("strategy")
+			if binary, _ := cmd.Flags().GetBool("binary"); binary {
+				strategy = deployStrategyBinary
+			}
+			var dockerFlags []string
+			for _, name := range dockerDeployFlags {
+				if cmd.Flags().Changed(name) {
+					dockerFlags = append(dockerFlags, "--"+name)
+				}
+			}
+			if resolveDeployStrategy(strategy, dockerFlags) == deployStrategyBinary {
+				arch, _ := cmd.Flags().GetString("arch")
+				cgo, _ := cmd.Flags().GetBool("cgo")
+				trimpath, _ := cmd.Flags().GetBool("trimpath")
+				ldflags, _ := cmd.Flags().GetString("ldflags")
+				buildTags, _ := cmd.Flags().GetStringSlice("build-tags")
+				main, _ := cmd.Flags().GetString("main")
+				opts := &http.BinaryBuildOptions{
+					Arch:     arch,
+					Cgo:      cgo,
+					Trimpath: trimpath,
+					LdFlags:  ldflags,
+					Tags:     buildTags,
+					Main:     main,
+				}
+				if !cmd.Flags().Changed("cgo") {
+					if cfg, err := readAppConfig(); err == nil {
+						opts.Cgo = cfg.Binary.Cgo
+					}
+				}
+				if !cmd.Flags().Changed("trimpath") {
+					opts.Trimpath = true
+					if cfg, err := readAppConfig(); err == nil && cfg.Binary.Trimpath != nil {
+						opts.Trimpath = *cfg.Binary.Trimpath
+					}
+				}
+				compression, _ := cmd.Flags().GetString("compression")
+				deployApp(opts, compression)
+				return
+			}
+			registry, _ := cmd.Flags().GetString("registry")
+			dockerfile, _ := cmd.Flags().GetString("dockerfile")
+			buildArgs, _ := cmd.Flags().GetStringArray("build-arg")
+			target, _ := cmd.Flags().GetString("target")
+			noCache, _ := cmd.Flags().GetBool("no-cache")
+			platform, _ := cmd.Flags().GetString("platform")
+			tag, _ := cmd.Flags().GetString("tag")
+			if tag != "" {
+				if err := ops.ValidateTag(tag); err != nil {
+					color.Red(err.Error())
+					os.Exit(1)
+				}
+			}
+			buildArgsMap := make(map[string]string)
+			for _, arg := range buildArgs {
+				kv := strings.SplitN(arg, "=", 2)
+				if len(kv) != 2 {
+					color.Red(fmt.Sprintf("invalid build arg format: %s, expected format is KEY=VALUE", arg))
+					os.Exit(1)
+				}
+				buildArgsMap[kv[0]] = kv[1]
+			}
+			// an explicit --no-cache=false wins over no_cache in csail.yml
+			if !cmd.Flags().Changed("no-cache") {
+				if cfg, err := readAppConfig(); err == nil {
+					noCache = cfg.Build.NoCache
+				}
+			}
+			remoteBuild, _ := cmd.Flags().GetBool("remote-build")
+			runDockerDeploy(&ops.BuildOptions{
+				Registry:   registry,
+				Tag:        tag,
+				Dockerfile: dockerfile,
+				BuildArgs:  buildArgsMap,
+				Target:     target,
+				NoCache:    noCache,
+				Platform:   platform,
+			}, remoteBuild)
 		},
 		Short: "Deploy or update application deployment.",
 		Long: "`hostgo deploy` will pack and deploy your application to hostgolang.com",
 	}
+	deploymentCmd.Flags().Bool("skip-checks", false, "skip the predeploy checks of csail.yml")
+	deploymentCmd.Flags().String("strategy", "", "how to package the app: binary, docker or auto. auto uses docker when a Dockerfile exists or a docker flag like --dockerfile is passed")
+	deploymentCmd.Flags().Bool("binary", false, "shorthand for --strategy binary")
+	deploymentCmd.Flags().String("arch", "", "binary strategy: target architecture, amd64 or arm64")
+	deploymentCmd.Flags().Bool("cgo", false, "binary strategy: build with CGO_ENABLED=1")
+	deploymentCmd.Flags().Bool("trimpath", true, "binary strategy: remove file system paths from the binary")
+	deploymentCmd.Flags().String("ldflags", "", "binary strategy: extra flags passed to the go linker")
+	deploymentCmd.Flags().StringSlice("build-tags", nil, "binary strategy: comma separated go build tags")
+	deploymentCmd.Flags().String("compression", "", "binary strategy: upload compression, gzip or zstd. gzip by default")
+	deploymentCmd.Flags().String("main", "", "binary strategy: path of the main package to build, e.g ./cmd/server")
+	deploymentCmd.Flags().String("dockerfile", "", "path to the Dockerfile to build, relative to the app directory")
+	deploymentCmd.Flags().StringArray("build-arg", nil, "build-time variable in KEY=VALUE format, can be repeated")
+	deploymentCmd.Flags().String("target", "", "target build stage of a multi-stage Dockerfile")
+	deploymentCmd.Flags().Bool("no-cache", false, "do not use cache when building the image")
+	deploymentCmd.Flags().String("platform", "", "target platform of the image, e.g linux/amd64")
+	deploymentCmd.Flags().String("tag", "", "image tag to deploy, defaults to the current git commit or, outside of git, a hash of the app files")
+	deploymentCmd.Flags().String("registry", "", "registry to push the built image to, defaults to "+ops.DefaultRegistry)
+	deploymentCmd.Flags().Bool("remote-build", false, "build the image on hostgo instead of the local docker daemon. Used automatically when docker is not reachable")
+	deploymentCmd.Flags().String("image", "", "deploy an already built image, e.g ghcr.io/org/app:sha, instead of building one")
 
 	scaleCmd := &cobra.Command{
-		Use: "scale",
+		Use: "scale [process=instances...]",
 		Run: func(cmd *cobra.Command, args []string) {
 			i, _ := cmd.Flags().GetInt32("instances")
-			if i < 1 {
+			size, _ := cmd.Flags().GetString("size")
+			memory, _ := cmd.Flags().GetString("memory")
+			cpu, _ := cmd.Flags().GetFloat64("cpu")
+			if cmd.Flags().Changed("instances") && i < 1 {
 				color.Red("invalid instance count. Instance should be at least 1")
 				os.Exit(1)
 			}
-			scaleApp(i)
+			processes, err := ops.ParseProcessCounts(args)
+			if err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			req := &types.ScaleRequest{Instances: i, Processes: processes, Size: size, Memory: memory, Cpu: cpu}
+			if i == 0 && len(processes) == 0 && size == "" && memory == "" && cpu == 0 {
+				color.Red("nothing to scale. run `hostgo scale -i {instances}`, `hostgo scale web=3 worker=2` or `hostgo scale --size {size}`")
+				os.Exit(1)
+			}
+			if err := ops.ValidateScaleRequest(req); err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			noWait, _ := cmd.Flags().GetBool("no-wait")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			scaleApp(req, noWait, timeout)
 		},
 		Short: "Scale application instances",
-		Long: "You can run `hostgo scale -i {instances}` to scale your app horizontally.",
+		Long: "You can run `hostgo scale -i {instances}` to scale your app horizontally, `hostgo scale web=3 worker=2` to scale the process types of csail.yml independently, and `hostgo scale --size {size}` or `hostgo scale --memory 512Mi --cpu 0.5` to scale it vertically. Run `hostgo sizes` to list the available sizes.",
+	}
+	sizesCmd := &cobra.Command{
+		Use: "sizes",
+		Run: func(cmd *cobra.Command, args []string) {
+			listSizes()
+		},
+		Short: "List available instance sizes and their price",
 	}
 	psCmd := &cobra.Command{
 		Use: "ps",
 		Run: func(cmd *cobra.Command, args []string) {
-			listInstances()
+			output, _ := cmd.Flags().GetString("output")
+			if output != "" && output != "table" && output != "json" {
+				color.Red("unknown output format %s. expected table or json", output)
+				os.Exit(1)
+			}
+			watch, _ := cmd.Flags().GetBool("watch")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			if interval < time.Second {
+				color.Red("interval should be at least 1s")
+				os.Exit(1)
+			}
+			listInstances(output, watch, interval)
 		},
 		Short: "Print running application instances",
 	}
+	psCmd.Flags().BoolP("watch", "w", false, "refresh the list in place until interrupted")
+	psCmd.Flags().Duration("interval", 2*time.Second, "refresh interval of --watch")
+	psCmd.Flags().StringP("output", "o", "table", "output format, table or json")
 	addDomainRootCmd := &cobra.Command{
 		Use: "domain",
 	}
@@ -135,9 +298,33 @@ func appsCmd() {
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) > 0 {
 				name := args[0]
-				provisionResource(name)
+				plan, _ := cmd.Flags().GetString("plan")
+				version, _ := cmd.Flags().GetString("version")
+				provisionResource(name, plan, version)
 			}
 		},
+		Short: "Add a resource, e.g postgres, to your app",
+		Long: "`hostgo resource add postgres --plan standard-1 --version 14` provisions a Postgres database for your app. Run `hostgo resource plans` to list the available plans and versions",
+	}
+	addResourceCmd.Flags().String("plan", "", "plan to provision, defaults to the smallest one")
+	addResourceCmd.Flags().String("version", "", "version to provision, defaults to the latest one")
+	listResourceCmd := &cobra.Command{
+		Use: "list",
+		Run: func(cmd *cobra.Command, args []string) {
+			listResources()
+		},
+		Short: "List resources attached to your app",
+	}
+	resourcePlansCmd := &cobra.Command{
+		Use: "plans [type]",
+		Run: func(cmd *cobra.Command, args []string) {
+			resType := ""
+			if len(args) > 0 {
+				resType = args[0]
+			}
+			listResourcePlans(resType)
+		},
+		Short: "List the resources, versions and plans you can provision",
 	}
 	removeResouceCmd := &cobra.Command{
 		Use: "remove",
@@ -157,10 +344,54 @@ func appsCmd() {
 			}
 		},
 	}
-	resourceCmd.AddCommand(addResourceCmd, removeResouceCmd, resourceDumpCmd)
+	resourceInfoCmd := &cobra.Command{
+		Use: "info <name>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("resource name is missing")
+				os.Exit(1)
+			}
+			format, _ := cmd.Flags().GetString("format")
+			if format != "" && format != "url" && format != "env" && format != "json" {
+				color.Red("unknown format %s. expected url, env or json", format)
+				os.Exit(1)
+			}
+			reveal, _ := cmd.Flags().GetBool("reveal")
+			resourceInfo(args[0], format, reveal)
+		},
+		Short: "Show connection info of a resource",
+		Long: "`hostgo resource info postgres` shows host, port, database and user of a resource, the password is masked unless --reveal is given. `--format url|env|json` prints the connection info for use in scripts, e.g `eval $(hostgo resource info postgres --format env --reveal)`",
+	}
+	resourceInfoCmd.Flags().Bool("reveal", false, "show the password")
+	resourceInfoCmd.Flags().StringP("format", "f", "", "output format, url, env or json")
+	resourceCredentialsCmd := &cobra.Command{
+		Use: "credentials",
+		Short: "Manage resource credentials",
+	}
+	rotateCredentialsCmd := &cobra.Command{
+		Use: "rotate <name>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("resource name is missing")
+				os.Exit(1)
+			}
+			yes, _ := cmd.Flags().GetBool("yes")
+			rotateResourceCredentials(args[0], yes)
+		},
+		Short: "Rotate the password of a resource and update your app env",
+	}
+	rotateCredentialsCmd.Flags().BoolP("yes", "y", false, "rotate without asking for confirmation")
+	resourceCredentialsCmd.AddCommand(rotateCredentialsCmd)
+	resourceCmd.AddCommand(addResourceCmd, listResourceCmd, resourcePlansCmd, resourceInfoCmd, resourceCredentialsCmd,
+		removeResouceCmd, resourceDumpCmd)
 	scaleCmd.Flags().Int32P("instances", "i", 0, "number of instances to scale to")
+	scaleCmd.Flags().String("size", "", "instance size, see `hostgo sizes`")
+	scaleCmd.Flags().String("memory", "", "memory limit per instance, e.g 512Mi")
+	scaleCmd.Flags().Float64("cpu", 0, "cpu cores per instance, e.g 0.5")
+	scaleCmd.Flags().Bool("no-wait", false, "do not wait for the new instances to become ready")
+	scaleCmd.Flags().Duration("timeout", 5*time.Minute, "how long to wait for the new instances to become ready")
 	createCmd.Flags().StringP("name", "n", "", "Preferred app name")
-	rootCmd.AddCommand(createCmd, logsCmd, deploymentCmd, scaleCmd, psCmd,
+	rootCmd.AddCommand(createCmd, logsCmd, deploymentCmd, scaleCmd, sizesCmd, psCmd,
 		rollbackCmd, resourceCmd, addDomainRootCmd)
 }
 
@@ -222,12 +453,36 @@ func getAppLogs() {
 	fmt.Println()
 }
 
-func deployApp() {
+// mergeBinaryBuildOptions fills in any binary build option not given on the
+// command line from the binary section of the app config.
+func mergeBinaryBuildOptions(cfg *types.Config, opts *http.BinaryBuildOptions) {
+	if opts.Arch == "" {
+		opts.Arch = cfg.Binary.Arch
+	}
+	if opts.LdFlags == "" {
+		opts.LdFlags = cfg.Binary.LdFlags
+	}
+	if len(opts.Tags) == 0 {
+		opts.Tags = cfg.Binary.Tags
+	}
+	if opts.Main == "" {
+		opts.Main = cfg.Binary.Main
+	}
+	if opts.Version == "" {
+		wd, _ := os.Getwd()
+		if v, err := ops.ImageTag(wd); err == nil {
+			opts.Version = v
+		}
+	}
+}
+
+func deployApp(opts *http.BinaryBuildOptions, compression string) {
 	cfg, err := readAppConfig()
 	if err != nil {
 		color.Red("failed to read app config: ", err.Error())
 		os.Exit(1)
 	}
+	mergeBinaryBuildOptions(cfg, opts)
 	provider := auth.NewAuthProvider()
 	account, err := provider.CurrentAuth()
 	if err != nil {
@@ -238,7 +493,7 @@ func deployApp() {
 	s.Prefix = "packing app..."
 	s.Start()
 	deploymentClient := http.NewDeploymentClient(cfg.AppName, account)
-	if err := deploymentClient.BuildBinary(); err != nil {
+	if err := deploymentClient.BuildBinary(opts); err != nil {
 		color.Red(err.Error())
 		os.Exit(1)
 	}
@@ -246,19 +501,15 @@ func deployApp() {
 	fmt.Println(color.WhiteString("packing app...done"))
 	wd, _ := os.Getwd()
 	binPath := filepath.Join(wd, cfg.AppName)
-	ss := spinner.New(spinner.CharSets[4], 500 * time.Millisecond)
-	ss.Prefix = "creating deployment..."
-	ss.Start()
+	if compression == "" {
+		compression = cfg.Binary.Compression
+	}
+	fmt.Println(color.WhiteString("creating deployment..."))
 	startTime := time.Now()
 	r := &types.DeploymentResult{}
-	err = deploymentClient.DeployApp(binPath, r)
-	if err != nil {
-		fmt.Println()
-		color.Red(err.Error())
-		fmt.Println()
-		os.Exit(1)
-	}
-	ss.Stop()
+	runDeploy(cfg, account, func(settings *types.DeploySettings) error {
+		return deploymentClient.DeployApp(binPath, compression, settings, os.Stdout, r)
+	})
 	fmt.Println(color.WhiteString("creating deployment...done"))
 	fmt.Println("====")
 	message := fmt.Sprintf("%s", color.GreenString("Deployment updated! | https://%s.hostgoapp.com | %s:%s", cfg.AppName, cfg.AppName, r.Data.Version))
@@ -283,15 +534,87 @@ func dockerDeploy(dockerUrl string) {
 	}
 	ss := spinner.New(spinner.CharSets[4], 500 * time.Millisecond)
 	ss.Prefix = "creating deployment..."
-	ss.Start()
+	if cfg.Release == "" {
+		ss.Start()
+	}
 	op := ops.NewAppsOp(http.NewHttpClient(account))
-	s, err := op.DockerDeploy(cfg.AppName, dockerUrl)
+	var s string
+	runDeploy(cfg, account, func(settings *types.DeploySettings) error {
+		var err error
+		s, err = op.DockerDeploy(cfg.AppName, dockerUrl, settings)
+		ss.Stop()
+		return err
+	})
+	fmt.Println("Deployment Updated: ", color.GreenString(s))
+}
+
+// resolveDeployStrategy picks how the app is packaged. The command line wins
+// over the app config, and auto uses docker when there is a Dockerfile or any
+// of the docker only flags in dockerFlags was passed. Those flags are
+// rejected when binary was asked for, rather than silently dropped.
+func resolveDeployStrategy(strategy string, dockerFlags []string) string {
+	cfg, err := readAppConfig()
+	if strategy == "" && err == nil {
+		strategy = cfg.Strategy
+	}
+	switch strategy {
+	case "", deployStrategyAuto:
+		wd, _ := os.Getwd()
+		if len(dockerFlags) > 0 || ops.HasDockerfile(wd) || (err == nil && cfg.Build.Dockerfile != "") {
+			return deployStrategyDocker
+		}
+		return deployStrategyBinary
+	case deployStrategyBinary:
+		if len(dockerFlags) > 0 {
+			color.Red("%s only apply to the docker strategy. drop them or deploy with --strategy docker", strings.Join(dockerFlags, ", "))
+			os.Exit(1)
+		}
+		return strategy
+	case deployStrategyDocker:
+		return strategy
+	}
+	color.Red("unknown deploy strategy %s. expected binary, docker or auto", strategy)
+	os.Exit(1)
+	return ""
+}
+
+// deployImage deploys an image built outside of hostgo. The image is pinned
+// to the digest the registry serves, so the deployment can not change under
+// us if the tag is moved.
+func deployImage(image string) {
+	cfg, err := readAppConfig()
 	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	loading := "verifying image " + image + "..."
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = loading
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	digest, err := op.ResolveImage(cfg.AppName, image)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
 		color.Red(err.Error())
-		return
+		os.Exit(1)
 	}
-	ss.Stop()
-	fmt.Println("Deployment Updated: ", color.GreenString(s))
+	ref, err := ops.PinImage(image, digest)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	s.Stop()
+	fmt.Println(color.WhiteString(loading + "done"))
+	dockerDeploy(ref)
 }
 
 func createAppConfigFile(appName string) error {
@@ -310,6 +633,57 @@ func createAppConfigFile(appName string) error {
 	return ioutil.WriteFile(filename, d, os.ModePerm)
 }
 
+// updateAppConfig sets key of csail.yml to value. Only that key is touched,
+// comments, key order and keys the cli does not know about are kept.
+func updateAppConfig(key string, value interface{}) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(wd, appConfigFileName)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, doc); err != nil {
+		return err
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yamlv3.MappingNode {
+		return fmt.Errorf("%s is not a yaml mapping", appConfigFileName)
+	}
+	root := doc.Content[0]
+	valueNode := &yamlv3.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+	found := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			// keep comments attached to the old value
+			valueNode.HeadComment = root.Content[i+1].HeadComment
+			valueNode.LineComment = root.Content[i+1].LineComment
+			valueNode.FootComment = root.Content[i+1].FootComment
+			root.Content[i+1] = valueNode
+			found = true
+			break
+		}
+	}
+	if !found {
+		root.Content = append(root.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: key}, valueNode)
+	}
+	buf := &bytes.Buffer{}
+	enc := yamlv3.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
 func readAppConfig() (*types.Config, error) {
 	c := &types.Config{}
 	wd, err := os.Getwd()
@@ -327,7 +701,7 @@ func readAppConfig() (*types.Config, error) {
 	return c, nil
 }
 
-func scaleApp(instance int32) {
+func scaleApp(req *types.ScaleRequest, noWait bool, timeout time.Duration) {
 	cfg, err := readAppConfig()
 	if err != nil {
 		color.Red("failed to read app config: ", err.Error())
@@ -343,13 +717,56 @@ func scaleApp(instance int32) {
 	s.Prefix = "working..."
 	s.Start()
 
-	type serverResponse struct {
-		Error bool
-		Message string
+	for name := range req.Processes {
+		if _, ok := cfg.Processes[name]; !ok && !(len(cfg.Processes) == 0 && name == ops.DefaultProcessType) {
+			s.Stop()
+			color.Red("unknown process type %s. declare it under processes in %s", name, appConfigFileName)
+			os.Exit(1)
+		}
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	before, err := op.ListInstances(cfg.AppName)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		fmt.Println()
+		os.Exit(1)
+	}
+	r, err := op.Scale(cfg.AppName, req)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		fmt.Println()
+		os.Exit(1)
+	}
+	if !noWait {
+		_, err = op.WaitForInstances(cfg.AppName, timeout, func(instances []types.Instance) bool {
+			if req.Instances > 0 && len(instances) != int(req.Instances) {
+				return false
+			}
+			counts := processCounts(instances)
+			for name, n := range req.Processes {
+				if counts[name] != int(n) {
+					return false
+				}
+			}
+			memory, _ := ops.MemoryBytes(req.Memory)
+			for _, instance := range instances {
+				if req.Size != "" && instance.Size != req.Size {
+					return false
+				}
+				if memory != 0 && instance.MemoryLimit != memory {
+					return false
+				}
+				if req.Cpu != 0 && instance.CpuLimit != req.Cpu {
+					return false
+				}
+			}
+			return allRunning(instances)
+		})
 	}
-	srvResponse := &serverResponse{}
-	httpClient := http.NewHttpClient(account)
-	err = httpClient.Do(fmt.Sprintf("/apps/scale/%s?replicas=%d", cfg.AppName, instance), "GET", nil, srvResponse)
 	s.Stop()
 	fmt.Println(color.WhiteString("working...done"))
 	if err != nil {
@@ -358,10 +775,105 @@ func scaleApp(instance int32) {
 		fmt.Println()
 		os.Exit(1)
 	}
-	color.Green(srvResponse.Message)
+	color.Green(r)
+	if req.Instances > 0 {
+		fmt.Printf("instances: %d -> %s\n", len(before), color.GreenString("%d", req.Instances))
+	}
+	counts := processCounts(before)
+	for _, name := range sortedKeys(req.Processes) {
+		fmt.Printf("%s: %d -> %s\n", name, counts[name], color.GreenString("%d", req.Processes[name]))
+	}
+	if req.Size != "" || req.Memory != "" || req.Cpu != 0 {
+		// a size and explicit memory or cpu are exclusive, whichever was
+		// given last replaces the other
+		if req.Size != "" {
+			cfg.Instance = types.InstanceConfig{Size: req.Size}
+		} else {
+			cfg.Instance.Size = ""
+		}
+		if req.Memory != "" {
+			cfg.Instance.Memory = req.Memory
+		}
+		if req.Cpu != 0 {
+			cfg.Instance.Cpu = req.Cpu
+		}
+		if err := updateAppConfig("instance", cfg.Instance); err != nil {
+			color.Red("failed to save instance size to %s: %s", appConfigFileName, err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("instance size: %s\n", color.GreenString(instanceSizeString(cfg.Instance)))
+	}
+}
+
+// processCounts counts instances per process type.
+func processCounts(instances []types.Instance) map[string]int {
+	counts := make(map[string]int)
+	for _, instance := range instances {
+		counts[processType(instance)]++
+	}
+	return counts
+}
+
+func processType(instance types.Instance) string {
+	if instance.Process == "" {
+		return ops.DefaultProcessType
+	}
+	return instance.Process
+}
+
+func sortedKeys(m map[string]int32) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func instanceSizeString(instance types.InstanceConfig) string {
+	if instance.Size != "" {
+		return instance.Size
+	}
+	parts := make([]string, 0, 2)
+	if instance.Cpu != 0 {
+		parts = append(parts, fmt.Sprintf("%v cpu", instance.Cpu))
+	}
+	if instance.Memory != "" {
+		parts = append(parts, instance.Memory)
+	}
+	return strings.Join(parts, ", ")
 }
 
-func listInstances() {
+func listSizes() {
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	sizes, err := op.ListSizes()
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	current := ""
+	if cfg, err := readAppConfig(); err == nil {
+		current = cfg.Instance.Size
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SIZE\tCPU\tMEMORY\tPRICE")
+	for _, size := range sizes {
+		name := size.Name
+		if name == current {
+			name += " (current)"
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\t$%.2f/month\n", name, size.Cpu, size.Memory, size.PricePerMonth)
+	}
+	w.Flush()
+}
+
+func listInstances(output string, watch bool, interval time.Duration) {
 	cfg, err := readAppConfig()
 	if err != nil {
 		color.Red("failed to read app config: ", err.Error())
@@ -373,39 +885,114 @@ func listInstances() {
 		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
 		os.Exit(1)
 	}
-	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
-	s.Prefix = "working..."
-	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		if !watch {
+			enc.SetIndent("", "  ")
+		}
+		for {
+			instances, err := op.ListInstances(cfg.AppName)
+			if err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			if instances == nil {
+				instances = []types.Instance{}
+			}
+			enc.Encode(instances)
+			if !watch {
+				return
+			}
+			time.Sleep(interval)
+		}
+	}
+	if !watch {
+		s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+		s.Prefix = "working..."
+		s.Start()
+		buf := &bytes.Buffer{}
+		err := printInstances(buf, cfg, op)
+		s.Stop()
+		fmt.Println(color.WhiteString("working...done"))
+		if err != nil {
+			fmt.Println()
+			color.Red(err.Error())
+			fmt.Println()
+			os.Exit(1)
+		}
+		buf.WriteTo(os.Stdout)
+		return
+	}
+	// redraw in place until interrupted, keeping the last table on screen
+	// when a refresh fails
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		buf := &bytes.Buffer{}
+		if err := printInstances(buf, cfg, op); err != nil {
+			fmt.Printf("\r%s", color.RedString("refresh failed: %s", err.Error()))
+		} else {
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Every %s, last update %s. Press Ctrl+C to stop\n\n", interval, time.Now().Format("15:04:05"))
+			buf.WriteTo(os.Stdout)
+		}
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			fmt.Println()
+			return
+		}
+	}
+}
 
-	httpClient := http.NewHttpClient(account)
-	type serverResponse struct {
-		Error bool
-		Message string
-		Data []struct{
-			Id string `json:"id"`
-			Name string `json:"name"`
-			Status string `json:"status"`
-			Started string `json:"started"`
-		} `json:"data"`
-	}
-	srvResponse := &serverResponse{}
-	err = httpClient.Do(fmt.Sprintf("/apps/ps/%s", cfg.AppName), "GET", nil, srvResponse)
-	s.Stop()
-	fmt.Println(color.WhiteString("working...done"))
+// printInstances writes the instances of the app to w, grouped by process
+// type.
+func printInstances(w io.Writer, cfg *types.Config, op *ops.AppsOp) error {
+	instances, err := op.ListInstances(cfg.AppName)
 	if err != nil {
-		fmt.Println()
-		color.Red(err.Error())
-		fmt.Println()
-		os.Exit(1)
+		return err
 	}
-	fmt.Println("ID\t\tNAME\t\tSTATUS\t\tSTARTED")
-	for _, p := range srvResponse.Data {
-		fmt.Println(fmt.Sprintf("%s\t\t%s\t\t%s\t\t%s", p.Id, p.Name, p.Status, p.Started))
+	if autoscale, err := op.GetAutoscale(cfg.AppName); err == nil && autoscale.Enabled {
+		fmt.Fprintf(w, "%d instance(s), set by autoscaling (%d - %d)\n\n", len(instances), autoscale.Policy.Min, autoscale.Policy.Max)
+	} else {
+		fmt.Fprintf(w, "%d instance(s), set manually\n\n", len(instances))
 	}
-	fmt.Println()
+	groups := make(map[string][]types.Instance)
+	names := make([]string, 0)
+	for _, p := range instances {
+		name := processType(p)
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], p)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		command := ""
+		if process, ok := cfg.Processes[name]; ok {
+			command = ": " + process.Command
+		}
+		fmt.Fprintln(w, color.CyanString("=== %s (%d)%s", name, len(groups[name]), command))
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tRELEASE\tSIZE\tCPU\tMEMORY\tRESTARTS\tUPTIME")
+		for _, p := range groups[name] {
+			memory := units.HumanBytes(p.Memory)
+			if p.MemoryLimit > 0 {
+				memory += " / " + units.HumanBytes(p.MemoryLimit)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.1f%%\t%s\t%d\t%s\n",
+				p.Id, p.Name, p.Status, p.Release, p.Size, p.Cpu, memory, p.Restarts, uptime(p.Started))
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+	return nil
 }
 
-func provisionResource(name string) {
+func provisionResource(name, plan, version string) {
 	cfg, err := readAppConfig()
 	if err != nil {
 		color.Red("failed to read app config: ", err.Error())
@@ -424,7 +1011,19 @@ func provisionResource(name string) {
 
 	httpClient := http.NewHttpClient(account)
 	op := ops.NewAppsOp(httpClient)
-	r, err := op.ProvisionResource(cfg.AppName, name)
+	if plan != "" || version != "" {
+		// without the catalogue the server is left to validate plan and version
+		if plans, err := op.ListResourcePlans(name); err == nil {
+			if err := ops.ValidateResourcePlan(plans, name, plan, version); err != nil {
+				s.Stop()
+				fmt.Println()
+				color.Red(err.Error())
+				fmt.Println()
+				os.Exit(1)
+			}
+		}
+	}
+	r, err := op.ProvisionResource(cfg.AppName, name, plan, version)
 	if err != nil {
 		fmt.Println()
 		color.Red(err.Error())
@@ -434,6 +1033,75 @@ func provisionResource(name string) {
 	s.Stop()
 	fmt.Println(color.WhiteString(loading + "done"))
 	fmt.Println(color.GreenString(r))
+	fmt.Printf("Run `hostgo resource info %s` to see its connection info\n", name)
+}
+
+func listResources() {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	resources, err := op.ListResources(cfg.AppName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if len(resources) == 0 {
+		fmt.Println("no resources. Run `hostgo resource plans` to see what you can add")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tVERSION\tPLAN\tSTATUS\tID")
+	for _, r := range resources {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.Type, r.Version, r.Plan, r.Status, r.Id)
+	}
+	w.Flush()
+}
+
+func listResourcePlans(resType string) {
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	plans, err := op.ListResourcePlans(resType)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if len(plans) == 0 && resType == "" {
+		fmt.Println("no resources are available to provision")
+		return
+	}
+	if len(plans) == 0 {
+		color.Red("no plans found for %s. run `hostgo resource plans` to list the available resources", resType)
+		os.Exit(1)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tPLAN\tVERSIONS\tCPU\tMEMORY\tSTORAGE\tCONNECTIONS\tPRICE")
+	for _, p := range plans {
+		versions := make([]string, len(p.Versions))
+		for i, v := range p.Versions {
+			versions[i] = v
+			if v == p.DefaultVersion {
+				versions[i] += "*"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%s\t%s\t%d\t$%.2f/month\n", p.Type, p.Name, strings.Join(versions, ", "),
+			p.Cpu, p.Memory, p.Storage, p.Connections, p.PricePerMonth)
+	}
+	w.Flush()
+	fmt.Println("\n* default version")
 }
 
 func removeResource(name string) {
@@ -557,33 +1225,113 @@ func dumpDatabase(resName string) {
 	fmt.Println(color.GreenString(r))
 }
 
-func runDockerDeploy() {
+// mergeBuildOptions fills in any build option not given on the command line
+// from the build section of the app config.
+func mergeBuildOptions(cfg *types.Config, opts *ops.BuildOptions) {
+	if opts.Registry == "" {
+		opts.Registry = cfg.Registry
+	}
+	if opts.Dockerfile == "" {
+		opts.Dockerfile = cfg.Build.Dockerfile
+	}
+	if opts.Target == "" {
+		opts.Target = cfg.Build.Target
+	}
+	if opts.Platform == "" {
+		opts.Platform = cfg.Build.Platform
+	}
+	if opts.BuildArgs == nil {
+		opts.BuildArgs = make(map[string]string)
+	}
+	for k, v := range cfg.Build.Args {
+		if _, ok := opts.BuildArgs[k]; !ok {
+			opts.BuildArgs[k] = v
+		}
+	}
+}
+
+func runDockerDeploy(opts *ops.BuildOptions, remoteBuild bool) {
 	cfg, err := readAppConfig()
 	if err != nil {
 		color.Red("failed to read app config: ", err.Error())
 		os.Exit(1)
 	}
+	mergeBuildOptions(cfg, opts)
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
 	loading := "working..."
 	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
 	s.Prefix = loading
 	s.Start()
 
-	op, err := ops.NewDockerOps()
+	wd, err := os.Getwd()
 	if err != nil {
-		fmt.Println(color.RedString(err.Error()))
+		color.Red(err.Error())
 		os.Exit(1)
 	}
+	if opts.Tag == "" {
+		if opts.Tag, err = ops.ImageTag(wd); err != nil {
+			fmt.Println(color.RedString(err.Error()))
+			os.Exit(1)
+		}
+	}
+	if !remoteBuild && !ops.DockerAvailable() {
+		s.Stop()
+		fmt.Println(color.YellowString("docker daemon is not reachable, building remotely"))
+		s.Start()
+		remoteBuild = true
+	}
+	var op ops.DockerService
+	if !remoteBuild {
+		op, err = ops.NewDockerOps(ops.NewRegistryOp(http.NewHttpClient(account)))
+		if err != nil {
+			fmt.Println(color.RedString(err.Error()))
+			os.Exit(1)
+		}
+	}
+	ref := ops.ImageRef(opts.Registry, cfg.AppName, opts.Tag)
+	exists := false
+	if remoteBuild {
+		_, err := ops.NewAppsOp(http.NewHttpClient(account)).ResolveImage(cfg.AppName, ref)
+		exists = err == nil
+	} else {
+		exists = op.ImageExists(ref)
+	}
 	s.Stop()
+	if exists {
+		fmt.Println(color.WhiteString("image %s already exists, skipping build and push", ref))
+		dockerDeploy(ref)
+		return
+	}
+
+	if opts.Dockerfile == "" && !ops.HasDockerfile(wd) {
+		generated, err := ops.GenerateDockerfile(wd, cfg.AppName)
+		if err != nil {
+			fmt.Println(color.RedString(err.Error()))
+			os.Exit(1)
+		}
+		opts.GeneratedDockerfile = generated
+	}
+	opts.Labels = ops.ImageLabels(wd, version)
+	if remoteBuild {
+		ref = runRemoteBuild(wd, cfg, account, opts, ref)
+	} else {
+		ref = runLocalBuild(wd, cfg, op, opts)
+	}
+	dockerDeploy(ref)
+}
+
+// runLocalBuild builds the image with the local docker daemon and pushes it.
+// It returns the reference of the pushed image.
+func runLocalBuild(wd string, cfg *types.Config, op ops.DockerService, opts *ops.BuildOptions) string {
 	b := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
 	b.Prefix = "Building image..."
 	b.Start()
-
-	wd, err := os.Getwd()
-	if err != nil {
-		color.Red(err.Error())
-		os.Exit(1)
-	}
-	ref, err := op.BuildImage(wd, cfg.AppName)
+	ref, err := op.BuildImage(wd, cfg.AppName, opts)
 	if err != nil {
 		fmt.Println(color.RedString(err.Error()))
 		os.Exit(1)
@@ -602,5 +1350,33 @@ func runDockerDeploy() {
 	}
 	p.Stop()
 	fmt.Println(color.WhiteString(pPrefix + "done"))
-	dockerDeploy(ref)
-}
\ No newline at end of file
+	return ref
+}
+
+// runRemoteBuild uploads the build context to the hostgo build service and
+// streams the build output. It returns the reference of the pushed image.
+func runRemoteBuild(wd string, cfg *types.Config, account *types.Account, opts *ops.BuildOptions, ref string) string {
+	buildCtx, dockerfile, err := ops.BuildContext(wd, opts)
+	if err != nil {
+		fmt.Println(color.RedString(err.Error()))
+		os.Exit(1)
+	}
+	defer buildCtx.Close()
+	fmt.Println(color.WhiteString("Uploading source and building remotely..."))
+	deploymentClient := http.NewDeploymentClient(cfg.AppName, account)
+	image, err := deploymentClient.RemoteBuild(buildCtx, &types.BuildRequest{
+		Image:      ref,
+		Dockerfile: dockerfile,
+		BuildArgs:  opts.BuildArgs,
+		Target:     opts.Target,
+		NoCache:    opts.NoCache,
+		Platform:   opts.Platform,
+		Labels:     opts.Labels,
+	}, os.Stdout)
+	if err != nil {
+		fmt.Println(color.RedString(err.Error()))
+		os.Exit(1)
+	}
+	fmt.Println(color.WhiteString("building image...done"))
+	return image
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+func autoscaleCmd() {
+	aCmd := &cobra.Command{
+		Use: "autoscale",
+		Short: "Manage the autoscaling policy of your app",
+	}
+	setCmd := &cobra.Command{
+		Use: "set",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := readAppConfig()
+			if err != nil {
+				color.Red("failed to read app config: ", err.Error())
+				os.Exit(1)
+			}
+			// flags override the policy declared in csail.yml
+			policy := &types.AutoscalePolicy{}
+			if cfg.Autoscale != nil {
+				*policy = *cfg.Autoscale
+			}
+			if cmd.Flags().Changed("min") {
+				policy.Min, _ = cmd.Flags().GetInt32("min")
+			}
+			if cmd.Flags().Changed("max") {
+				policy.Max, _ = cmd.Flags().GetInt32("max")
+			}
+			if cmd.Flags().Changed("cpu") {
+				policy.TargetCpu, _ = cmd.Flags().GetInt("cpu")
+			}
+			if cmd.Flags().Changed("rps") {
+				policy.TargetRps, _ = cmd.Flags().GetInt("rps")
+			}
+			if err := ops.ValidateAutoscalePolicy(policy); err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			setAutoscale(cfg, policy)
+		},
+		Short: "Enable autoscaling or update the policy",
+		Long: "`hostgo autoscale set --min 2 --max 10 --cpu 70 --rps 500` keeps between 2 and 10 instances running, adding instances when average cpu goes above 70% or an instance serves more than 500 requests per second. The policy is saved to the autoscale section of csail.yml, which every deploy applies, and values not given default to that section. Remove that section to keep autoscaling disabled across deploys",
+	}
+	showCmd := &cobra.Command{
+		Use: "show",
+		Run: func(cmd *cobra.Command, args []string) {
+			showAutoscale()
+		},
+		Short: "Show the autoscaling policy and its last decision",
+	}
+	disableCmd := &cobra.Command{
+		Use: "disable",
+		Run: func(cmd *cobra.Command, args []string) {
+			disableAutoscale()
+		},
+		Short: "Disable autoscaling, keeping the current instance count",
+		Long: "`hostgo autoscale disable` keeps the current instance count. A policy declared in csail.yml is enabled again by the next deploy",
+	}
+	setCmd.Flags().Int32("min", 0, "minimum number of instances")
+	setCmd.Flags().Int32("max", 0, "maximum number of instances")
+	setCmd.Flags().Int("cpu", 0, "target average cpu usage in percent")
+	setCmd.Flags().Int("rps", 0, "target requests per second per instance")
+	aCmd.AddCommand(setCmd, showCmd, disableCmd)
+	rootCmd.AddCommand(aCmd)
+}
+
+func setAutoscale(cfg *types.Config, policy *types.AutoscalePolicy) {
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = "working..."
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.SetAutoscale(cfg.AppName, policy)
+	s.Stop()
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println(color.WhiteString("working...done"))
+	color.Green(r)
+	// deploys apply the policy of csail.yml, save it there so the next one
+	// does not revert it
+	if err := updateAppConfig("autoscale", policy); err != nil {
+		color.Red("failed to save autoscale policy to %s: %s", appConfigFileName, err.Error())
+		os.Exit(1)
+	}
+	printAutoscalePolicy(policy)
+}
+
+func showAutoscale() {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	status, err := op.GetAutoscale(cfg.AppName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if !status.Enabled {
+		fmt.Println("autoscaling is disabled. Run `hostgo autoscale set` to enable it")
+		return
+	}
+	printAutoscalePolicy(&status.Policy)
+	fmt.Printf("current instances: %d\n", status.Instances)
+	if status.LastScaledAt != nil {
+		fmt.Printf("last scaled: %s (%s)\n", status.LastScaledAt.Local().Format(time.RFC1123), status.LastScaleReason)
+	}
+}
+
+func disableAutoscale() {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.DisableAutoscale(cfg.AppName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	color.Green(r)
+}
+
+func printAutoscalePolicy(policy *types.AutoscalePolicy) {
+	fmt.Printf("instances: %d - %d\n", policy.Min, policy.Max)
+	if policy.TargetCpu > 0 {
+		fmt.Printf("cpu target: %d%%\n", policy.TargetCpu)
+	}
+	if policy.TargetRps > 0 {
+		fmt.Printf("rps target: %d per instance\n", policy.TargetRps)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/manifoldco/promptui"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func cronCmd() {
+	cCmd := &cobra.Command{
+		Use: "cron",
+		Short: "Manage scheduled jobs of your app",
+	}
+	addCmd := &cobra.Command{
+		Use: "add <schedule> -- <command>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 2 || cmd.ArgsLenAtDash() != 1 {
+				color.Red("schedule or command is missing. run `hostgo cron add \"*/5 * * * *\" -- ./app cleanup`")
+				os.Exit(1)
+			}
+			schedule, err := ops.ParseCronSchedule(args[0])
+			if err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			next, _ := cmd.Flags().GetInt("next")
+			if next < 1 {
+				color.Red("--next should be at least 1")
+				os.Exit(1)
+			}
+			yes, _ := cmd.Flags().GetBool("yes")
+			addCronJob(args[0], schedule, args[1:], next, yes)
+		},
+		Short: "Schedule a command to run with your app image and env",
+		Long: "`hostgo cron add \"*/5 * * * *\" -- ./app cleanup` runs `./app cleanup` every 5 minutes. Schedules are standard 5 field cron expressions evaluated in UTC, the next fire times are shown before the job is created",
+	}
+	addCmd.Flags().Int("next", 5, "number of upcoming fire times to show")
+	addCmd.Flags().BoolP("yes", "y", false, "create the job without asking for confirmation")
+	listCmd := &cobra.Command{
+		Use: "list",
+		Run: func(cmd *cobra.Command, args []string) {
+			listCronJobs()
+		},
+		Short: "List scheduled jobs",
+	}
+	removeCmd := &cobra.Command{
+		Use: "remove <job-id>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("job id is missing. run `hostgo cron list` to find it")
+				os.Exit(1)
+			}
+			removeCronJob(args[0])
+		},
+		Short: "Remove a scheduled job",
+	}
+	runNowCmd := &cobra.Command{
+		Use: "run-now <job-id>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("job id is missing. run `hostgo cron list` to find it")
+				os.Exit(1)
+			}
+			runCronJob(args[0])
+		},
+		Short: "Run a scheduled job immediately",
+	}
+	historyCmd := &cobra.Command{
+		Use: "history <job-id>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("job id is missing. run `hostgo cron list` to find it")
+				os.Exit(1)
+			}
+			limit, _ := cmd.Flags().GetInt("limit")
+			cronHistory(args[0], limit)
+		},
+		Short: "Show recent runs of a scheduled job",
+	}
+	historyCmd.Flags().IntP("limit", "n", 20, "number of runs to show")
+	cCmd.AddCommand(addCmd, listCmd, removeCmd, runNowCmd, historyCmd)
+	rootCmd.AddCommand(cCmd)
+}
+
+func addCronJob(expr string, schedule *ops.CronSchedule, command []string, next int, yes bool) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	times := schedule.NextN(time.Now(), next)
+	if len(times) == 0 {
+		color.Red("schedule %s never fires", expr)
+		os.Exit(1)
+	}
+	fmt.Printf("%s will run `%s`, next at:\n", expr, strings.Join(command, " "))
+	for _, t := range times {
+		fmt.Printf("  %s (%s)\n", t.Format("Mon 2006-01-02 15:04 MST"), t.Local().Format("15:04 MST"))
+	}
+	fmt.Println()
+	if !yes && isTerminal() {
+		prompt := promptui.Prompt{
+			Label:     "Create this job",
+			IsConfirm: true,
+		}
+		if _, err := prompt.Run(); err != nil {
+			os.Exit(1)
+		}
+	}
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = "working..."
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	job, err := op.AddCronJob(cfg.AppName, &types.CronJob{Schedule: expr, Command: command})
+	s.Stop()
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println(color.WhiteString("working...done"))
+	color.Green("cron job created | %s", job.Id)
+}
+
+func listCronJobs() {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	jobs, err := op.ListCronJobs(cfg.AppName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if len(jobs) == 0 {
+		fmt.Println("no cron jobs. Run `hostgo cron add` to schedule one")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCHEDULE\tCOMMAND\tNEXT RUN\tLAST RUN")
+	for _, job := range jobs {
+		nextRun := "-"
+		if job.NextRun != nil {
+			nextRun = job.NextRun.Local().Format(time.RFC1123)
+		}
+		lastRun := "-"
+		if job.LastRun != nil {
+			lastRun = fmt.Sprintf("%s (%s)", job.LastRun.StartedAt.Local().Format(time.RFC1123), cronRunStatus(job.LastRun))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", job.Id, job.Schedule, strings.Join(job.Command, " "), nextRun, lastRun)
+	}
+	w.Flush()
+}
+
+func removeCronJob(jobId string) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.RemoveCronJob(cfg.AppName, jobId)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	color.Green(r)
+}
+
+func runCronJob(jobId string) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	run, err := op.RunCronJob(cfg.AppName, jobId)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	color.Green("cron job started | %s", run.Id)
+	fmt.Printf("Run `hostgo cron history %s` to see how it went\n", jobId)
+}
+
+func cronHistory(jobId string, limit int) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	runs, err := op.CronHistory(cfg.AppName, jobId, limit)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if len(runs) == 0 {
+		fmt.Println("the job has not run yet")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "RUN\tSTARTED\tDURATION\tSTATUS\tTRIGGER")
+	for _, run := range runs {
+		duration := "-"
+		if run.FinishedAt != nil {
+			duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Second).String()
+		}
+		trigger := "schedule"
+		if run.Manual {
+			trigger = "manual"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", run.Id, run.StartedAt.Local().Format(time.RFC1123), duration, cronRunStatus(&run), trigger)
+	}
+	w.Flush()
+}
+
+func cronRunStatus(run *types.CronRun) string {
+	if run.FinishedAt == nil {
+		return run.Status
+	}
+	if run.ExitCode != 0 {
+		return fmt.Sprintf("%s, exit %d", run.Status, run.ExitCode)
+	}
+	return run.Status
+}
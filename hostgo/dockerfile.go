@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func dockerfileCmd() {
+	dCmd := &cobra.Command{
+		Use: "dockerfile",
+		Short: "Manage the Dockerfile used to build your app",
+	}
+	generateCmd := &cobra.Command{
+		Use: "generate",
+		Run: func(cmd *cobra.Command, args []string) {
+			output, _ := cmd.Flags().GetString("output")
+			force, _ := cmd.Flags().GetBool("force")
+			generateDockerfile(output, force)
+		},
+		Short: "Write the Dockerfile hostgo would generate for this app",
+		Long: "`hostgo dockerfile generate` writes the Dockerfile used when your app has none, so you can customise it",
+	}
+	generateCmd.Flags().StringP("output", "o", "Dockerfile", "file to write the Dockerfile to")
+	generateCmd.Flags().BoolP("force", "f", false, "overwrite the output file if it exists")
+	dCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(dCmd)
+}
+
+func generateDockerfile(output string, force bool) {
+	wd, err := os.Getwd()
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	appName := filepath.Base(wd)
+	if cfg, err := readAppConfig(); err == nil {
+		appName = cfg.AppName
+	}
+	if _, err := os.Stat(output); err == nil && !force {
+		color.Red("%s already exists. Use --force to overwrite it", output)
+		os.Exit(1)
+	}
+	data, err := ops.GenerateDockerfile(wd, appName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(output, data, 0644); err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println(color.GreenString("Dockerfile written to %s", output))
+}
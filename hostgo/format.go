@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// humanDuration formats d with its two most significant units, e.g 3d4h or
+// 12m5s.
+func humanDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm%ds", minutes, seconds)
+}
+
+// uptime returns how long ago started was, started being a RFC3339 time as
+// served by the api. Unparsable values are returned as they are.
+func uptime(started string) string {
+	t, err := time.Parse(time.RFC3339, started)
+	if err != nil {
+		return started
+	}
+	return humanDuration(time.Since(t))
+}
+
+// shellQuote single quotes s for a POSIX shell, so output meant for `eval`
+// keeps characters like & and ; in the value.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
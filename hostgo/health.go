@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func healthCmd() {
+	hCmd := &cobra.Command{
+		Use: "health",
+		Run: func(cmd *cobra.Command, args []string) {
+			failures, _ := cmd.Flags().GetInt("failures")
+			if failures < 0 {
+				color.Red("--failures should not be negative")
+				os.Exit(1)
+			}
+			showHealth(failures)
+		},
+		Short: "Show the health of your app instances",
+		Long: "`hostgo health` shows the health check of your app, the health state of each instance and its most recent failed checks",
+	}
+	hCmd.Flags().IntP("failures", "n", 3, "number of recent failures to show per instance")
+	setCmd := &cobra.Command{
+		Use: "set",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := readAppConfig()
+			if err != nil {
+				color.Red("failed to read app config: ", err.Error())
+				os.Exit(1)
+			}
+			// flags override the check declared in csail.yml
+			check := &types.HealthCheck{}
+			if cfg.HealthCheck != nil {
+				*check = *cfg.HealthCheck
+			}
+			if cmd.Flags().Changed("path") {
+				check.Path, _ = cmd.Flags().GetString("path")
+			}
+			if cmd.Flags().Changed("port") {
+				check.Port, _ = cmd.Flags().GetInt("port")
+			}
+			if cmd.Flags().Changed("interval") {
+				interval, _ := cmd.Flags().GetDuration("interval")
+				check.Interval = int(interval.Seconds())
+			}
+			if cmd.Flags().Changed("timeout") {
+				timeout, _ := cmd.Flags().GetDuration("timeout")
+				check.Timeout = int(timeout.Seconds())
+			}
+			if cmd.Flags().Changed("healthy-threshold") {
+				check.HealthyThreshold, _ = cmd.Flags().GetInt("healthy-threshold")
+			}
+			if cmd.Flags().Changed("unhealthy-threshold") {
+				check.UnhealthyThreshold, _ = cmd.Flags().GetInt("unhealthy-threshold")
+			}
+			if err := ops.ValidateHealthCheck(check); err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			setHealthCheck(cfg, check)
+		},
+		Short: "Set the health check of your app",
+		Long: "`hostgo health set --path /healthz --interval 10s --timeout 2s` checks every instance on /healthz. New instances receive traffic once they pass the check, and a deploy whose instances never do is not rolled out. The check is saved to the health_check section of csail.yml, which every deploy applies, and values not given default to that section",
+	}
+	setCmd.Flags().String("path", "", "HTTP path to check, defaults to /")
+	setCmd.Flags().Int("port", 0, "port to check, defaults to the port traffic is routed to")
+	setCmd.Flags().Duration("interval", 0, "time between checks, defaults to 10s")
+	setCmd.Flags().Duration("timeout", 0, "time a check may take, defaults to 5s")
+	setCmd.Flags().Int("healthy-threshold", 0, "successful checks in a row before an instance is healthy, defaults to 2")
+	setCmd.Flags().Int("unhealthy-threshold", 0, "failed checks in a row before an instance is unhealthy, defaults to 3")
+	hCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(hCmd)
+}
+
+func setHealthCheck(cfg *types.Config, check *types.HealthCheck) {
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = "working..."
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.SetHealthCheck(cfg.AppName, check)
+	s.Stop()
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println(color.WhiteString("working...done"))
+	color.Green(r)
+	// deploys apply the check of csail.yml, save it there so the next one
+	// does not revert it
+	if err := updateAppConfig("health_check", check); err != nil {
+		color.Red("failed to save health check to %s: %s", appConfigFileName, err.Error())
+		os.Exit(1)
+	}
+	printHealthCheck(ops.WithHealthCheckDefaults(check))
+}
+
+func showHealth(failures int) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	status, err := op.GetHealth(cfg.AppName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if status.Check == nil {
+		fmt.Println("no health check. Add health_check to csail.yml or run `hostgo health set`")
+		return
+	}
+	printHealthCheck(status.Check)
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATE\tLAST CHECK")
+	for _, instance := range status.Instances {
+		lastCheck := "-"
+		if instance.LastCheckedAt != nil {
+			lastCheck = humanDuration(time.Since(*instance.LastCheckedAt)) + " ago"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", instance.InstanceId, instance.Name, healthState(instance.State), lastCheck)
+	}
+	w.Flush()
+	for _, instance := range status.Instances {
+		if len(instance.Failures) == 0 || failures == 0 {
+			continue
+		}
+		fmt.Printf("\nrecent failures of %s:\n", instance.Name)
+		recent := instance.Failures
+		if len(recent) > failures {
+			recent = recent[:failures]
+		}
+		for _, f := range recent {
+			reason := f.Error
+			if f.StatusCode != 0 {
+				reason = fmt.Sprintf("HTTP %d %s", f.StatusCode, f.Error)
+			}
+			fmt.Printf("  %s  %s\n", f.Time.Local().Format(time.RFC1123), color.RedString(reason))
+		}
+	}
+}
+
+func healthState(state string) string {
+	switch state {
+	case "healthy":
+		return color.GreenString(state)
+	case "unhealthy":
+		return color.RedString(state)
+	}
+	return color.YellowString(state)
+}
+
+func printHealthCheck(check *types.HealthCheck) {
+	target := check.Path
+	if check.Port != 0 {
+		target = fmt.Sprintf(":%d%s", check.Port, check.Path)
+	}
+	fmt.Printf("check: GET %s every %ds, timeout %ds\n", target, check.Interval, check.Timeout)
+	fmt.Printf("healthy after %d passes, unhealthy after %d failures\n", check.HealthyThreshold, check.UnhealthyThreshold)
+}
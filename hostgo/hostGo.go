@@ -5,14 +5,26 @@ import (
 	"github.com/spf13/cobra"
 	"os"
 )
+// version is overridden at release time with -ldflags "-X main.version=..."
+var version = "dev"
+
 var rootCmd = &cobra.Command{
 	Use:   "hostgo",
 	Short: "Cloud hosting for Go web applications",
+	Version: version,
 }
 func main() {
 	appsCmd()
 	authCmd()
 	envCmd()
+	dockerfileCmd()
+	registryCmd()
+	runCmd()
+	lifecycleCmd()
+	autoscaleCmd()
+	cronCmd()
+	metricsCmd()
+	healthCmd()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+func lifecycleCmd() {
+	restartCmd := &cobra.Command{
+		Use: "restart [instance-id]",
+		Run: func(cmd *cobra.Command, args []string) {
+			instanceId := ""
+			if len(args) > 0 {
+				instanceId = args[0]
+			}
+			allAtOnce, _ := cmd.Flags().GetBool("all-at-once")
+			noWait, _ := cmd.Flags().GetBool("no-wait")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			restartApp(instanceId, !allAtOnce, noWait, timeout)
+		},
+		Short: "Restart an app instance, or the whole app",
+		Long: "`hostgo restart` restarts all instances of your app one at a time and waits for them to become healthy. Pass an instance id from `hostgo ps` to restart a single instance",
+	}
+	stopCmd := &cobra.Command{
+		Use: "stop",
+		Run: func(cmd *cobra.Command, args []string) {
+			stopApp()
+		},
+		Short: "Stop all app instances, keeping the app configuration",
+	}
+	startCmd := &cobra.Command{
+		Use: "start",
+		Run: func(cmd *cobra.Command, args []string) {
+			noWait, _ := cmd.Flags().GetBool("no-wait")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			startApp(noWait, timeout)
+		},
+		Short: "Start a stopped app with the instance count it had before",
+	}
+	restartCmd.Flags().Bool("all-at-once", false, "restart every instance at the same time instead of one by one")
+	for _, c := range []*cobra.Command{restartCmd, startCmd} {
+		c.Flags().Bool("no-wait", false, "do not wait for instances to become healthy")
+		c.Flags().Duration("timeout", 5*time.Minute, "how long to wait for instances to become healthy")
+	}
+	rootCmd.AddCommand(restartCmd, stopCmd, startCmd)
+}
+
+func restartApp(instanceId string, rolling, noWait bool, timeout time.Duration) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	loading := "restarting " + cfg.AppName + "..."
+	if instanceId != "" {
+		loading = "restarting " + instanceId + "..."
+	}
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = loading
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	before, err := op.ListInstances(cfg.AppName)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	r, err := op.Restart(cfg.AppName, instanceId, rolling)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if !noWait {
+		_, err = op.WaitForInstances(cfg.AppName, timeout, func(after []types.Instance) bool {
+			return len(after) >= len(before) && allRunning(after) && restarted(before, after, instanceId)
+		})
+		if err != nil {
+			s.Stop()
+			fmt.Println()
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+	}
+	s.Stop()
+	fmt.Println(color.WhiteString(loading + "done"))
+	fmt.Println(color.GreenString(r))
+}
+
+func stopApp() {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	loading := "stopping " + cfg.AppName + "..."
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = loading
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.Stop(cfg.AppName)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	s.Stop()
+	fmt.Println(color.WhiteString(loading + "done"))
+	fmt.Println(color.GreenString(r))
+}
+
+func startApp(noWait bool, timeout time.Duration) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	loading := "starting " + cfg.AppName + "..."
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = loading
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	count, err := op.Start(cfg.AppName)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if !noWait {
+		_, err = op.WaitForInstances(cfg.AppName, timeout, func(instances []types.Instance) bool {
+			return len(instances) >= count && allRunning(instances)
+		})
+		if err != nil {
+			s.Stop()
+			fmt.Println()
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+	}
+	s.Stop()
+	fmt.Println(color.WhiteString(loading + "done"))
+	fmt.Println(color.GreenString("%s is running with %d instance(s)", cfg.AppName, count))
+}
+
+func allRunning(instances []types.Instance) bool {
+	for _, instance := range instances {
+		if !ops.IsRunning(instance) {
+			return false
+		}
+	}
+	return true
+}
+
+// restarted reports whether the instances in before were replaced or
+// restarted, only looking at instanceId when it is not empty.
+func restarted(before, after []types.Instance, instanceId string) bool {
+	started := make(map[string]string, len(after))
+	for _, instance := range after {
+		started[instance.Id] = instance.Started
+	}
+	for _, instance := range before {
+		if instanceId != "" && instance.Id != instanceId {
+			continue
+		}
+		if s, ok := started[instance.Id]; ok && s == instance.Started {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"github.com/saas/hostgo/pkg/units"
+	"github.com/spf13/cobra"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsPoints is the number of points requested per series, the width of
+// the sparklines.
+const metricsPoints = 60
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+func metricsCmd() {
+	mCmd := &cobra.Command{
+		Use: "metrics",
+		Run: func(cmd *cobra.Command, args []string) {
+			sinceFlag, _ := cmd.Flags().GetString("since")
+			since, err := parseSince(sinceFlag)
+			if err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			output, _ := cmd.Flags().GetString("output")
+			if output != "table" && output != "json" {
+				color.Red("unknown output format %s. expected table or json", output)
+				os.Exit(1)
+			}
+			showMetrics(since, output)
+		},
+		Short: "Show request rate, latency, errors and resource usage of your app",
+		Long: "`hostgo metrics --since 1h` draws a sparkline per metric over the last hour. Use `--output json` to get the raw series",
+	}
+	mCmd.Flags().String("since", "1h", "time window to show, e.g 15m, 6h or 7d")
+	mCmd.Flags().StringP("output", "o", "table", "output format, table or json")
+	rootCmd.AddCommand(mCmd)
+}
+
+// parseSince parses a time.Duration, also accepting whole days like 7d.
+func parseSince(since string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if strings.HasSuffix(since, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(since, "d"))
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(since)
+	}
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("invalid --since %s. expected a window of at least 1m, e.g 15m, 6h or 7d", since)
+	}
+	return d, nil
+}
+
+func showMetrics(since time.Duration, output string) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	step := since / metricsPoints
+	if step < 10*time.Second {
+		step = 10 * time.Second
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	if output == "json" {
+		metrics, err := op.Metrics(cfg.AppName, since, step)
+		if err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(metrics)
+		return
+	}
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = "working..."
+	s.Start()
+	metrics, err := op.Metrics(cfg.AppName, since, step)
+	s.Stop()
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println(color.WhiteString("working...done"))
+	fmt.Printf("%s, %s - %s\n\n", cfg.AppName, metrics.From.Local().Format("Jan 2 15:04"), metrics.To.Local().Format("Jan 2 15:04"))
+	formatMs := func(v float64) string { return fmt.Sprintf("%.0fms", v) }
+	formatPercent := func(v float64) string { return fmt.Sprintf("%.1f%%", v) }
+	printMetric("rps", metrics.Rps, func(v float64) string { return fmt.Sprintf("%.1f", v) })
+	printMetric("latency p50", metrics.LatencyP50, formatMs)
+	printMetric("latency p95", metrics.LatencyP95, formatMs)
+	printMetric("latency p99", metrics.LatencyP99, formatMs)
+	printMetric("error rate", metrics.ErrorRate, formatPercent)
+	printMetric("cpu", metrics.Cpu, formatPercent)
+	printMetric("memory", metrics.Memory, func(v float64) string { return units.HumanBytes(int64(v)) })
+	fmt.Println()
+}
+
+// printMetric prints one line per metric: a sparkline scaled between the
+// minimum and maximum of the series, followed by the last value and range.
+func printMetric(name string, points []types.MetricPoint, format func(float64) string) {
+	if len(points) == 0 {
+		fmt.Printf("%-12s %s\n", name, color.WhiteString("no data"))
+		return
+	}
+	values := make([]float64, len(points))
+	min, max := math.Inf(1), math.Inf(-1)
+	for i, p := range points {
+		values[i] = p.Value
+		min = math.Min(min, p.Value)
+		max = math.Max(max, p.Value)
+	}
+	fmt.Printf("%-12s %s  %s  %s\n", name, color.CyanString(sparkline(values, min, max)),
+		color.GreenString("%8s", format(values[len(values)-1])),
+		color.WhiteString("(min %s, max %s)", format(min), format(max)))
+}
+
+func sparkline(values []float64, min, max float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > min {
+			i = int((v - min) / (max - min) * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// runPredeployChecks runs the predeploy commands of the app config one after
+// the other, exiting on the first failure so nothing broken gets deployed.
+func runPredeployChecks(steps []string) {
+	if len(steps) == 0 {
+		return
+	}
+	fmt.Println(color.WhiteString("running %d predeploy check(s)...", len(steps)))
+	total := time.Now()
+	for i, step := range steps {
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(steps), color.CyanString(step))
+		start := time.Now()
+		err := shellCommand(step).Run()
+		took := time.Since(start).Seconds()
+		if err != nil {
+			fmt.Println(color.RedString("[%d/%d] %s failed after %.2fsecs: %s", i+1, len(steps), step, took, err.Error()))
+			color.Red("\ndeploy aborted. Fix the failing check or run `hostgo deploy --skip-checks` to deploy anyway")
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString("[%d/%d] %s passed in %.2fsecs", i+1, len(steps), step, took))
+	}
+	fmt.Printf("\npredeploy checks passed in %s\n\n", color.GreenString("%.2fsecs", time.Since(total).Seconds()))
+}
+
+func shellCommand(command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
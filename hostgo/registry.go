@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/manifoldco/promptui"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func registryCmd() {
+	rCmd := &cobra.Command{
+		Use: "registry",
+		Short: "Manage access to the hostgo image registry",
+	}
+	loginCmd := &cobra.Command{
+		Use: "login",
+		Run: func(cmd *cobra.Command, args []string) {
+			registryLogin()
+		},
+		Short: "Log the docker cli in to the hostgo registry",
+		Long: "`hostgo registry login` stores short-lived registry credentials in your docker credential store, so `docker push` and `docker pull` work against the hostgo registry",
+	}
+	rCmd.AddCommand(loginCmd)
+
+	credsCmd := &cobra.Command{
+		Use: "registry-credentials",
+		Run: func(cmd *cobra.Command, args []string) {
+			listPullSecrets()
+		},
+		Short: "List credentials used to pull images from private registries",
+	}
+	addCredsCmd := &cobra.Command{
+		Use: "add",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("registry is missing. run `hostgo registry-credentials add ghcr.io -u username`")
+				os.Exit(1)
+			}
+			username, _ := cmd.Flags().GetString("username")
+			passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
+			addPullSecret(args[0], username, passwordStdin)
+		},
+		Short: "Register credentials for a private registry",
+		Long: "`hostgo registry-credentials add ghcr.io -u username` lets hostgo pull your app images from a private registry",
+	}
+	removeCredsCmd := &cobra.Command{
+		Use: "remove",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("registry is missing")
+				os.Exit(1)
+			}
+			removePullSecret(args[0])
+		},
+		Short: "Remove the credentials of a private registry",
+	}
+	addCredsCmd.Flags().StringP("username", "u", "", "registry username")
+	addCredsCmd.Flags().Bool("password-stdin", false, "read the password or token from stdin")
+	credsCmd.AddCommand(addCredsCmd, removeCredsCmd)
+	rootCmd.AddCommand(rCmd, credsCmd)
+}
+
+func registryLogin() {
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil || account.Token == "" {
+		color.Red("\n\nYou have to be authenticated before you can access the registry. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = "working..."
+	s.Start()
+	op := ops.NewRegistryOp(http.NewHttpClient(account))
+	c, err := op.Credentials(true)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if err := ops.StoreDockerCredentials(c); err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red("failed to store docker credentials: %s", err.Error())
+		os.Exit(1)
+	}
+	s.Stop()
+	fmt.Println(color.WhiteString("working...done"))
+	fmt.Printf("Logged in to %s as %s\n", color.GreenString(c.Registry), color.GreenString(c.Username))
+	fmt.Printf("Credentials expire at %s\n", c.ExpiresAt.Local().Format(time.RFC1123))
+}
+
+func addPullSecret(registry, username string, passwordStdin bool) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	if username == "" {
+		promptUsername := promptui.Prompt{
+			Label: "Username",
+		}
+		username, _ = promptUsername.Run()
+	}
+	var password string
+	if passwordStdin {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		password = strings.TrimSpace(string(data))
+	} else {
+		promptPassword := promptui.Prompt{
+			Label: "Password",
+			Mask:  '*',
+		}
+		password, _ = promptPassword.Run()
+	}
+	if username == "" || password == "" {
+		color.Red("username and password are required")
+		os.Exit(1)
+	}
+
+	loading := "adding credentials for " + registry + "..."
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = loading
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.AddPullSecret(cfg.AppName, &types.PullSecret{Registry: registry, Username: username, Password: password})
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	s.Stop()
+	fmt.Println(color.WhiteString(loading + "done"))
+	fmt.Println(color.GreenString(r))
+}
+
+func listPullSecrets() {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	secrets, err := op.ListPullSecrets(cfg.AppName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "REGISTRY\tUSERNAME\tADDED")
+	for _, secret := range secrets {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", secret.Registry, secret.Username, secret.CreatedAt.Local().Format(time.RFC822))
+	}
+	w.Flush()
+}
+
+func removePullSecret(registry string) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	loading := "removing credentials for " + registry + "..."
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = loading
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.RemovePullSecret(cfg.AppName, registry)
+	if err != nil {
+		s.Stop()
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	s.Stop()
+	fmt.Println(color.WhiteString(loading + "done"))
+	fmt.Println(color.GreenString(r))
+}
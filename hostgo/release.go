@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"os"
+	"time"
+)
+
+// runDeploy validates the deploy settings of the app config and runs deploy
+// with them: the process types, health check and autoscaling policy, and the
+// release phase if there is one. The platform applies the settings with the
+// new release only, so a failed deploy leaves the running release as it is.
+// While deploy runs the release output is streamed back, and the process
+// exits when deploy fails, e.g because the release command did.
+func runDeploy(cfg *types.Config, account *types.Account, deploy func(settings *types.DeploySettings) error) {
+	settings := &types.DeploySettings{Processes: cfg.Processes}
+	if cfg.Autoscale != nil {
+		if err := ops.ValidateAutoscalePolicy(cfg.Autoscale); err != nil {
+			color.Red("invalid autoscale policy in %s: %s", appConfigFileName, err.Error())
+			os.Exit(1)
+		}
+		settings.Autoscale = cfg.Autoscale
+	}
+	if cfg.HealthCheck != nil {
+		if err := ops.ValidateHealthCheck(cfg.HealthCheck); err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		settings.HealthCheck = ops.WithHealthCheckDefaults(cfg.HealthCheck)
+	}
+	if err := ops.ValidateProcesses(cfg.Processes); err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if cfg.Release == "" {
+		if err := deploy(settings); err != nil {
+			fmt.Println()
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	release := &types.ReleasePhase{Id: newReleaseId(), Command: cfg.Release}
+	settings.Release = release
+	fmt.Println(color.WhiteString("running release command: %s", release.Command))
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		op := ops.NewAppsOp(http.NewHttpClient(account))
+		code, err := op.StreamReleaseLogs(cfg.AppName, release.Id, os.Stdout, stop)
+		if err != nil {
+			select {
+			case <-stop:
+				// deploy returned before the logs could be streamed, its
+				// error tells how the release went
+			default:
+				fmt.Println(color.YellowString("%s. the deploy goes on, its result tells whether the release command succeeded", err.Error()))
+			}
+			return
+		}
+		if code != 0 {
+			color.Red("release command exited with code %d", code)
+			return
+		}
+		fmt.Println(color.WhiteString("release command...done"))
+	}()
+	err := deploy(settings)
+	close(stop)
+	// the release has finished by the time deploy returns, give the tail of
+	// the log stream a moment to arrive
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+	}
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+}
+
+func newReleaseId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/manifoldco/promptui"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"os"
+	"time"
+)
+
+func resourceInfo(name, format string, reveal bool) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	creds, err := op.ResourceCredentials(cfg.AppName, name)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if !reveal {
+		creds = ops.MaskCredentials(creds)
+	}
+	switch format {
+	case "url":
+		fmt.Println(creds.Url)
+	case "env":
+		fmt.Printf("%s=%s\n", creds.EnvVar, shellQuote(creds.Url))
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(creds)
+	default:
+		fmt.Printf("type:     %s\n", creds.Type)
+		fmt.Printf("host:     %s\n", creds.Host)
+		fmt.Printf("port:     %d\n", creds.Port)
+		if creds.Database != "" {
+			fmt.Printf("database: %s\n", creds.Database)
+		}
+		if creds.User != "" {
+			fmt.Printf("user:     %s\n", creds.User)
+		}
+		fmt.Printf("password: %s\n", creds.Password)
+		fmt.Printf("env:      %s\n", creds.EnvVar)
+		if !reveal {
+			fmt.Println(color.WhiteString("\nuse --reveal to show the password"))
+		}
+	}
+}
+
+func rotateResourceCredentials(name string, yes bool) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	if !yes && !isTerminal() {
+		color.Red("pass --yes to rotate non-interactively")
+		os.Exit(1)
+	}
+	if !yes {
+		prompt := promptui.Prompt{
+			Label:     fmt.Sprintf("Rotate the password of %s? Your app is restarted with the new one and other clients lose access", name),
+			IsConfirm: true,
+		}
+		if _, err := prompt.Run(); err != nil {
+			os.Exit(1)
+		}
+	}
+	loading := "rotating credentials of " + name + "..."
+	s := spinner.New(spinner.CharSets[4], 200 * time.Millisecond)
+	s.Prefix = loading
+	s.Start()
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	r, err := op.RotateResourceCredentials(cfg.AppName, name)
+	s.Stop()
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println(color.WhiteString(loading + "done"))
+	color.Green(r)
+	fmt.Printf("Run `hostgo resource info %s --reveal` to see the new credentials\n", name)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/manifoldco/promptui"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/ops"
+	"github.com/saas/hostgo/pkg/types"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func runCmd() {
+	rCmd := &cobra.Command{
+		Use: "run -- <command>",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				color.Red("command to run is missing. run `hostgo run -- ./app migrate`")
+				os.Exit(1)
+			}
+			noTty, _ := cmd.Flags().GetBool("no-tty")
+			runOneOff(args, !noTty && isTerminal())
+		},
+		Short: "Run a one-off command in your app environment",
+		Long: "`hostgo run -- ./app migrate` starts a one-off instance of the current release with the app env and attaches your terminal to it",
+	}
+	rCmd.Flags().BoolP("no-tty", "T", false, "do not allocate a tty, even when attached to a terminal")
+	eCmd := &cobra.Command{
+		Use: "exec [instance-id] -- <command>",
+		Run: func(cmd *cobra.Command, args []string) {
+			before, command := args, []string{}
+			if dash := cmd.ArgsLenAtDash(); dash != -1 {
+				before, command = args[:dash], args[dash:]
+			}
+			if len(before) > 1 {
+				color.Red("expected at most one instance id before --. run `hostgo exec <instance-id> -- <command>`")
+				os.Exit(1)
+			}
+			instanceId := ""
+			if len(before) == 1 {
+				instanceId = before[0]
+			}
+			if len(command) == 0 {
+				command = []string{"sh"}
+			}
+			noTty, _ := cmd.Flags().GetBool("no-tty")
+			execInstance(instanceId, command, !noTty && isTerminal())
+		},
+		Short: "Run a command in a running app instance",
+		Long: "`hostgo exec <instance-id> -- sh` attaches your terminal to a command running in an instance listed by `hostgo ps`. You will be asked to pick an instance when no id is given",
+	}
+	eCmd.Flags().BoolP("no-tty", "T", false, "do not allocate a tty, even when attached to a terminal")
+	rootCmd.AddCommand(rCmd, eCmd)
+}
+
+func runOneOff(command []string, tty bool) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	req := &types.ProcessRequest{Command: command, Tty: tty}
+	if tty {
+		req.Width, req.Height = terminalSize()
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	session, err := op.Run(cfg.AppName, req)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	code, err := attachSession(session, tty)
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+func execInstance(instanceId string, command []string, tty bool) {
+	cfg, err := readAppConfig()
+	if err != nil {
+		color.Red("failed to read app config: ", err.Error())
+		os.Exit(1)
+	}
+	provider := auth.NewAuthProvider()
+	account, err := provider.CurrentAuth()
+	if err != nil {
+		color.Red("\n\nYou have to be authenticated before you can access an app. Run `hostgo login` to authenticate your account")
+		os.Exit(1)
+	}
+	op := ops.NewAppsOp(http.NewHttpClient(account))
+	if instanceId == "" {
+		instanceId = pickInstance(op, cfg.AppName)
+	}
+	req := &types.ProcessRequest{Command: command, Tty: tty}
+	if tty {
+		req.Width, req.Height = terminalSize()
+	}
+	session, err := op.Exec(cfg.AppName, instanceId, req)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	code, err := attachSession(session, tty)
+	if err != nil {
+		fmt.Println()
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+// pickInstance asks the user to choose one of the running instances of an
+// app, skipping the question when there is only one.
+func pickInstance(op *ops.AppsOp, appName string) string {
+	all, err := op.ListInstances(appName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	instances := make([]types.Instance, 0, len(all))
+	for _, instance := range all {
+		if ops.IsRunning(instance) {
+			instances = append(instances, instance)
+		}
+	}
+	if len(instances) == 0 {
+		color.Red("%s has no running instances", appName)
+		os.Exit(1)
+	}
+	if len(instances) == 1 {
+		return instances[0].Id
+	}
+	items := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		items = append(items, fmt.Sprintf("%s  %s  %s", instance.Id, instance.Name, instance.Status))
+	}
+	prompt := promptui.Select{
+		Label: "Instance",
+		Items: items,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		os.Exit(1)
+	}
+	return instances[i].Id
+}
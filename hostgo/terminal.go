@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/docker/docker/pkg/term"
+	"github.com/saas/hostgo/pkg/http"
+	"os"
+)
+
+// isTerminal reports whether both stdin and stdout are terminals, i.e an
+// attached process can be given a tty.
+func isTerminal() bool {
+	inFd, inTerm := term.GetFdInfo(os.Stdin)
+	outFd, outTerm := term.GetFdInfo(os.Stdout)
+	return inTerm && outTerm && term.IsTerminal(inFd) && term.IsTerminal(outFd)
+}
+
+// terminalSize returns the width and height of the terminal on stdout.
+func terminalSize() (uint16, uint16) {
+	fd, _ := term.GetFdInfo(os.Stdout)
+	ws, err := term.GetWinsize(fd)
+	if err != nil {
+		return 0, 0
+	}
+	return ws.Width, ws.Height
+}
+
+// attachSession connects the local terminal to a remote process until it
+// exits and returns its exit code. With tty the local terminal is put in raw
+// mode, and size changes are forwarded to the remote process.
+func attachSession(session *http.Session, tty bool) (int, error) {
+	if tty {
+		fd, _ := term.GetFdInfo(os.Stdin)
+		state, err := term.SetRawTerminal(fd)
+		if err == nil {
+			defer term.RestoreTerminal(fd, state)
+		}
+		resize := func() {
+			if w, h := terminalSize(); w > 0 && h > 0 {
+				session.Resize(w, h)
+			}
+		}
+		resize()
+		stop := watchResize(resize)
+		defer stop()
+	}
+	return session.Stream(os.Stdin, os.Stdout, os.Stderr)
+}
@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "time"
+
+// watchResize calls resize whenever the terminal is resized, until the
+// returned function is called. Windows has no SIGWINCH, so the terminal
+// size is polled.
+func watchResize(resize func()) func() {
+	done := make(chan struct{})
+	go func() {
+		w, h := terminalSize()
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if nw, nh := terminalSize(); nw != w || nh != h {
+					w, h = nw, nh
+					resize()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
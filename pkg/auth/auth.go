@@ -29,15 +29,24 @@ func NewAuthProvider() AuthProvider {
 	return &defaultAuthProvider{}
 }
 
-func (d *defaultAuthProvider) CurrentAuth() (*types.Account, error) {
+// ConfigDir returns the directory hostgo keeps its state in.
+func ConfigDir() (string, error) {
 	h, err := homedir.Dir()
 	if err != nil {
 		h = os.Getenv("HOME_DIR")
 		if h == "" {
-			return nil, ErrNoHomeDir
+			return "", ErrNoHomeDir
 		}
 	}
-	authFile := filepath.Join(h, authDirName, authFileName)
+	return filepath.Join(h, authDirName), nil
+}
+
+func (d *defaultAuthProvider) CurrentAuth() (*types.Account, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	authFile := filepath.Join(dir, authFileName)
 	data, err := ioutil.ReadFile(authFile)
 	if err != nil {
 		return nil, ErrNoAuth
@@ -50,14 +59,10 @@ func (d *defaultAuthProvider) CurrentAuth() (*types.Account, error) {
 }
 
 func (d *defaultAuthProvider) CreateAuth(account *types.Account) error {
-	h, err := homedir.Dir()
+	authFolder, err := ConfigDir()
 	if err != nil {
-		h = os.Getenv("HOME_DIR")
-		if h == "" {
-			return ErrNoHomeDir
-		}
+		return err
 	}
-	authFolder := filepath.Join(h, authDirName)
 	if _, err := os.Stat(authFolder); err == nil {
 		if err := os.RemoveAll(authFolder); err != nil {
 			return errors.New("failed to authenticate account: " + err.Error())
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/saas/hostgo/pkg/types"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// buildMessage is a single line of the newline delimited JSON stream returned
+// by the remote build service.
+type buildMessage struct {
+	Stream string `json:"stream"`
+	Error  string `json:"error"`
+	Image  string `json:"image"`
+}
+
+// RemoteBuild uploads a tar build context to the build service, which builds
+// and pushes the image described by req. The context goes through a resumable
+// upload, and build output is streamed to logs as it arrives. It returns the
+// reference of the pushed image.
+func (s *DeploymentClient) RemoteBuild(buildCtx io.Reader, req *types.BuildRequest, logs io.Writer) (string, error) {
+	path, checksum, err := stageUpload(buildCtx, CompressionGzip)
+	if err != nil {
+		return "", err
+	}
+	req.UploadId, err = NewUploadClient(s.appName, s.account).Upload(path, UploadKindSource, checksum, logs)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	serverUrl := fmt.Sprintf("%s/apps/build/%s", serverUrl, s.appName)
+	httpReq, err := http.NewRequest("POST", serverUrl, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	if s.account != nil {
+		httpReq.Header.Set("X-Auth-Token", s.account.Token)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	r, err := newStreamingClient(longRunningIdleTimeout).Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(r.Body)
+		srvResponse := &struct {
+			Message string `json:"message"`
+		}{}
+		if err := json.Unmarshal(body, srvResponse); err != nil || srvResponse.Message == "" {
+			return "", fmt.Errorf("remote build failed. server returned http code %d", r.StatusCode)
+		}
+		return "", errors.New(srvResponse.Message)
+	}
+
+	image := ""
+	dec := json.NewDecoder(r.Body)
+	for {
+		m := &buildMessage{}
+		if err := dec.Decode(m); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if m.Error != "" {
+			return "", errors.New(m.Error)
+		}
+		if m.Stream != "" {
+			fmt.Fprint(logs, m.Stream)
+		}
+		if m.Image != "" {
+			image = m.Image
+		}
+	}
+	if image == "" {
+		return "", errors.New("remote build finished without producing an image")
+	}
+	return image, nil
+}
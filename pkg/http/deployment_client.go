@@ -1,10 +1,13 @@
 package http
 
 import (
-	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/klauspost/compress/zstd"
 	"github.com/saas/hostgo/pkg/types"
 	"io"
 	"io/ioutil"
@@ -12,7 +15,8 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"time"
+	"path/filepath"
+	"strings"
 )
 
 // DeploymentClient
@@ -25,7 +29,9 @@ type DeploymentClient struct {
 
 func NewDeploymentClient(appName string, account *types.Account) *DeploymentClient {
 	cmd := NewCmdClient(appName)
-	httpClient := &http.Client{Timeout: 60 * time.Second}
+	// the deploy response only arrives once the release command and rollout
+	// are done
+	httpClient := newStreamingClient(longRunningIdleTimeout)
 	return &DeploymentClient{
 		cmd:        cmd,
 		httpClient: httpClient,
@@ -38,29 +44,54 @@ func (s *DeploymentClient) DockerDeploy(result *types.DeploymentResult) error {
 	return nil
 }
 
-func (s *DeploymentClient) DeployApp(binPath string, result *types.DeploymentResult) error {
-	buf := &bytes.Buffer{}
-	writer := multipart.NewWriter(buf)
-	if err := writer.WriteField("app_name", s.appName); err != nil {
-		return err
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// DeployApp uploads the binary at binPath and deploys it. The binary is
+// compressed and streamed as it is read, so it is never held in memory, and
+// its SHA-256 checksum is sent along for the server to verify. Binaries
+// larger than a single upload chunk go through a resumable upload instead.
+// Upload progress is rendered to progress when it is not nil, settings go
+// along with the binary.
+func (s *DeploymentClient) DeployApp(binPath, compression string, settings *types.DeploySettings, progress io.Writer, result *types.DeploymentResult) error {
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	if compression != CompressionGzip && compression != CompressionZstd {
+		return fmt.Errorf("unsupported compression %s. expected gzip or zstd", compression)
 	}
 	in, err := os.Open(binPath)
 	if err != nil {
 		return err
 	}
-	out, err := writer.CreateFormFile("bin", binPath)
+	defer in.Close()
+	info, err := in.Stat()
 	if err != nil {
 		return err
 	}
-	if _, err := io.Copy(out, in); err != nil {
+	h := sha256.New()
+	if _, err := io.Copy(h, in); err != nil {
 		return err
 	}
-	if err := writer.Close(); err != nil {
+	checksum := hex.EncodeToString(h.Sum(nil))
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
 		return err
 	}
+	if info.Size() > chunkSize {
+		return s.deployLargeApp(in, checksum, compression, settings, progress, result)
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		src := newProgressReader(in, progress, "uploading", info.Size())
+		pw.CloseWithError(s.writeBinary(writer, binPath, checksum, compression, settings, src))
+	}()
 
 	serverUrl := fmt.Sprintf("%s/apps/deploy", serverUrl)
-	req, err := http.NewRequest("POST", serverUrl, buf)
+	req, err := http.NewRequest("POST", serverUrl, pr)
 	if err != nil {
 		return err
 	}
@@ -68,10 +99,12 @@ func (s *DeploymentClient) DeployApp(binPath string, result *types.DeploymentRes
 		req.Header.Set("X-Auth-Token", s.account.Token)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Content-Sha256", checksum)
 	r, err := s.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer r.Body.Close()
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return err
@@ -86,6 +119,95 @@ func (s *DeploymentClient) DeployApp(binPath string, result *types.DeploymentRes
 	return nil
 }
 
+// deployLargeApp deploys binaries too large to send in a single request
+// through a resumable upload.
+func (s *DeploymentClient) deployLargeApp(in io.Reader, checksum, compression string, settings *types.DeploySettings, progress io.Writer, result *types.DeploymentResult) error {
+	path, uploadChecksum, err := stageUpload(in, compression)
+	if err != nil {
+		return err
+	}
+	uploadId, err := NewUploadClient(s.appName, s.account).Upload(path, UploadKindBinary, uploadChecksum, progress)
+	if err != nil {
+		return err
+	}
+	type payload struct {
+		AppName     string `json:"app_name"`
+		UploadId    string `json:"upload_id"`
+		Sha256      string `json:"sha256"`
+		Compression string `json:"compression"`
+		*types.DeploySettings
+	}
+	p := &payload{AppName: s.appName, UploadId: uploadId, Sha256: checksum, Compression: compression, DeploySettings: settings}
+	return NewHttpClient(s.account).DoLongRunning("/apps/deploy", "POST", p, result)
+}
+
+func (s *DeploymentClient) writeBinary(writer *multipart.Writer, binPath, checksum, compression string, settings *types.DeploySettings, src io.Reader) error {
+	if err := writer.WriteField("app_name", s.appName); err != nil {
+		return err
+	}
+	if err := writer.WriteField("sha256", checksum); err != nil {
+		return err
+	}
+	if err := writer.WriteField("compression", compression); err != nil {
+		return err
+	}
+	if settings != nil {
+		// each setting is a json encoded field, named like in the payload of
+		// large deploys
+		fields := []struct {
+			name  string
+			set   bool
+			value interface{}
+		}{
+			{"release", settings.Release != nil, settings.Release},
+			{"processes", len(settings.Processes) > 0, settings.Processes},
+			{"health_check", settings.HealthCheck != nil, settings.HealthCheck},
+			{"autoscale", settings.Autoscale != nil, settings.Autoscale},
+		}
+		for _, field := range fields {
+			if !field.set {
+				continue
+			}
+			data, err := json.Marshal(field.value)
+			if err != nil {
+				return err
+			}
+			if err := writer.WriteField(field.name, string(data)); err != nil {
+				return err
+			}
+		}
+	}
+	out, err := writer.CreateFormFile("bin", filepath.Base(binPath)+"."+compressionExtension(compression))
+	if err != nil {
+		return err
+	}
+	cw, err := compressWriter(out, compression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, src); err != nil {
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func compressWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	if compression == CompressionZstd {
+		return zstd.NewWriter(w)
+	}
+	return gzip.NewWriter(w), nil
+}
+
+func compressionExtension(compression string) string {
+	if compression == CompressionZstd {
+		return "zst"
+	}
+	return "gz"
+}
+
 func buildImage(name, dir string) (string, error) {
 	cmd := exec.Command("docker", "build", "-t", name, dir)
 	if err := cmd.Run(); err != nil {
@@ -99,8 +221,24 @@ func buildImage(name, dir string) (string, error) {
 	return "", nil
 }
 
-func (s *DeploymentClient) BuildBinary() error {
-	return s.cmd.ExecBuildCommand()
+func (s *DeploymentClient) BuildBinary(opts *BinaryBuildOptions) error {
+	return s.cmd.ExecBuildCommand(opts)
+}
+
+// BinaryBuildOptions controls how the app binary is compiled. The target OS
+// is always linux, we deploy to linux containers.
+type BinaryBuildOptions struct {
+	// Arch is the target GOARCH, amd64 or arm64. amd64 when empty.
+	Arch     string
+	Cgo      bool
+	Trimpath bool
+	LdFlags  string
+	// Version is injected into the binary with -X main.version=...
+	Version string
+	Tags    []string
+	// Main is the path of the main package to build. The working directory
+	// when empty.
+	Main string
 }
 
 // env GOOS=linux go build -ldflags="-s -w" -o stormTest main.go
@@ -112,16 +250,47 @@ func NewCmdClient(appName string) *CmdClient {
 	return &CmdClient{appName: appName}
 }
 
-func (c *CmdClient) ExecBuildCommand() error {
-	// change build env to linux, we need linux container
-	if err := os.Setenv("GOOS", "linux"); err != nil {
-		return err
+func (c *CmdClient) ExecBuildCommand(opts *BinaryBuildOptions) error {
+	if opts == nil {
+		opts = &BinaryBuildOptions{}
+	}
+	arch := opts.Arch
+	if arch == "" {
+		arch = "amd64"
+	}
+	if arch != "amd64" && arch != "arm64" {
+		return fmt.Errorf("unsupported architecture %s. expected amd64 or arm64", arch)
+	}
+	args := []string{"build", "-o", c.appName}
+	if opts.Trimpath {
+		args = append(args, "-trimpath")
+	}
+	if len(opts.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(opts.Tags, ","))
+	}
+	ldflags := "-s -w"
+	if opts.Version != "" {
+		ldflags += " -X main.version=" + opts.Version
+	}
+	if opts.LdFlags != "" {
+		ldflags += " " + opts.LdFlags
+	}
+	args = append(args, "-ldflags", ldflags)
+	if opts.Main != "" {
+		args = append(args, opts.Main)
+	}
+	cgo := "0"
+	if opts.Cgo {
+		cgo = "1"
 	}
-	cmd := exec.Command("go", "build", "-o", c.appName)
+	cmd := exec.Command("go", args...)
 	wd, err := os.Getwd()
 	if err != nil {
 		return err
 	}
+	// we need a linux binary whatever the host is, set on the command so the
+	// rest of the process keeps its environment
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH="+arch, "CGO_ENABLED="+cgo)
 	cmd.Dir = wd
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/saas/hostgo/pkg/types"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
@@ -17,19 +18,39 @@ const serverUrl = "https://api.csail.app/api"
 
 type Client interface {
 	Do(endpoint, method string, payload, response interface{}) error
+	// DoLongRunning is Do for requests the server answers only once a long
+	// operation finished, e.g a deploy running a release command.
+	DoLongRunning(endpoint, method string, payload, response interface{}) error
 	DoRaw(endpoint, method string, payload interface{}) ([]byte, error)
+	Stream(endpoint, method string, payload interface{}) (io.ReadCloser, error)
+	Attach(endpoint string, payload interface{}) (*Session, error)
 }
 
 type defaultClient struct {
-	httpClient *http.Client
-	account    *types.Account
+	httpClient        *http.Client
+	longRunningClient *http.Client
+	attachClient      *http.Client
+	account           *types.Account
 }
 
 func NewHttpClient(account *types.Account) Client {
-	return &defaultClient{account: account, httpClient: &http.Client{Timeout: 60 * time.Second}}
+	return &defaultClient{
+		account:           account,
+		httpClient:        &http.Client{Timeout: 60 * time.Second},
+		longRunningClient: newStreamingClient(longRunningIdleTimeout),
+		attachClient:      newAttachClient(),
+	}
 }
 
 func (d *defaultClient) Do(endpoint, method string, payload, response interface{}) error {
+	return d.do(d.httpClient, endpoint, method, payload, response)
+}
+
+func (d *defaultClient) DoLongRunning(endpoint, method string, payload, response interface{}) error {
+	return d.do(d.longRunningClient, endpoint, method, payload, response)
+}
+
+func (d *defaultClient) do(client *http.Client, endpoint, method string, payload, response interface{}) error {
 	targetUrl := fmt.Sprintf("%s%s", serverUrl, endpoint)
 	p, err := json.Marshal(payload)
 	if err != nil {
@@ -42,7 +63,7 @@ func (d *defaultClient) Do(endpoint, method string, payload, response interface{
 	if d.account != nil {
 		req.Header.Set("X-Auth-Token", d.account.Token)
 	}
-	resp, err := d.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -94,3 +115,82 @@ func (d *defaultClient) DoRaw(endpoint, method string, payload interface{}) ([]b
 	}
 	return ioutil.ReadAll(resp.Body)
 }
+
+// Stream sends a request and returns the response body as it arrives, for
+// endpoints that stream logs or events. Those may go quiet for as long as a
+// long operation, e.g a release command running a migration, so the stream
+// uses the idle timeout of DoLongRunning. The caller must close the body.
+func (d *defaultClient) Stream(endpoint, method string, payload interface{}) (io.ReadCloser, error) {
+	targetUrl := fmt.Sprintf("%s%s", serverUrl, endpoint)
+	var body io.Reader
+	if payload != nil {
+		p, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(p)
+	}
+	req, err := http.NewRequest(method, targetUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	if d.account != nil {
+		req.Header.Set("X-Auth-Token", d.account.Token)
+	}
+	resp, err := d.longRunningClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		srvResponse := &struct {
+			Message string `json:"message"`
+		}{}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if err := json.Unmarshal(data, srvResponse); err != nil || srvResponse.Message == "" {
+			return nil, fmt.Errorf("server error. returned http code %d", resp.StatusCode)
+		}
+		return nil, errors.New(srvResponse.Message)
+	}
+	return resp.Body, nil
+}
+
+// Attach upgrades a request to a raw connection carrying an interactive
+// process, see Session.
+func (d *defaultClient) Attach(endpoint string, payload interface{}) (*Session, error) {
+	targetUrl := fmt.Sprintf("%s%s", serverUrl, endpoint)
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", targetUrl, bytes.NewBuffer(p))
+	if err != nil {
+		return nil, err
+	}
+	if d.account != nil {
+		req.Header.Set("X-Auth-Token", d.account.Token)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "hostgo-attach")
+	resp, err := d.attachClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		srvResponse := &struct {
+			Message string `json:"message"`
+		}{}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if err := json.Unmarshal(data, srvResponse); err != nil || srvResponse.Message == "" {
+			return nil, fmt.Errorf("server error. returned http code %d", resp.StatusCode)
+		}
+		return nil, errors.New(srvResponse.Message)
+	}
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, errors.New("server did not upgrade the connection")
+	}
+	return NewSession(conn), nil
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"fmt"
+	"github.com/saas/hostgo/pkg/units"
+	"io"
+	"strings"
+	"time"
+)
+
+const progressBarWidth = 30
+
+// progressReader renders a progress bar to out as the underlying reader is
+// consumed.
+type progressReader struct {
+	io.Reader
+	out      io.Writer
+	label    string
+	total    int64
+	read     int64
+	rendered time.Time
+}
+
+func newProgressReader(r io.Reader, out io.Writer, label string, total int64) *progressReader {
+	return &progressReader{Reader: r, out: out, label: label, total: total}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.read += int64(n)
+	if time.Since(p.rendered) > 100*time.Millisecond || err == io.EOF {
+		p.render()
+	}
+	return n, err
+}
+
+func (p *progressReader) render() {
+	p.rendered = time.Now()
+	if p.out == nil || p.total <= 0 {
+		return
+	}
+	done := int(float64(p.read) / float64(p.total) * progressBarWidth)
+	if done > progressBarWidth {
+		done = progressBarWidth
+	}
+	bar := strings.Repeat("=", done) + strings.Repeat(" ", progressBarWidth-done)
+	fmt.Fprintf(p.out, "\r%s [%s] %3d%% %s/%s", p.label, bar,
+		p.read*100/p.total, units.HumanBytes(p.read), units.HumanBytes(p.total))
+	if p.read >= p.total {
+		fmt.Fprintln(p.out)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Frames exchanged over an attached connection. Every frame is a one byte
+// type, a four byte big endian payload length and the payload.
+const (
+	frameStdin byte = iota
+	frameStdout
+	frameStderr
+	frameResize
+	frameExit
+)
+
+const maxFrameSize = 1 << 20
+
+// Session is an interactive process running on the platform, attached over
+// an upgraded http connection.
+type Session struct {
+	conn io.ReadWriteCloser
+	mu   sync.Mutex
+}
+
+func NewSession(conn io.ReadWriteCloser) *Session {
+	return &Session{conn: conn}
+}
+
+// Stream copies stdin to the remote process and its output to stdout and
+// stderr until the process exits. It returns the exit code of the process.
+func (s *Session) Stream(stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	defer s.conn.Close()
+	if stdin != nil {
+		go s.copyStdin(stdin)
+	}
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(s.conn, header); err != nil {
+			return 0, errors.New("connection to the remote process was lost")
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size > maxFrameSize {
+			return 0, fmt.Errorf("invalid frame of %d bytes from the remote process", size)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(s.conn, payload); err != nil {
+			return 0, errors.New("connection to the remote process was lost")
+		}
+		switch header[0] {
+		case frameStdout:
+			stdout.Write(payload)
+		case frameStderr:
+			stderr.Write(payload)
+		case frameExit:
+			if len(payload) != 4 {
+				return 0, errors.New("invalid exit frame from the remote process")
+			}
+			return int(int32(binary.BigEndian.Uint32(payload))), nil
+		}
+	}
+}
+
+// Resize tells the remote process the size of the local terminal.
+func (s *Session) Resize(width, height uint16) error {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint16(payload, width)
+	binary.BigEndian.PutUint16(payload[2:], height)
+	return s.writeFrame(frameResize, payload)
+}
+
+func (s *Session) copyStdin(stdin io.Reader) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			if werr := s.writeFrame(frameStdin, buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			// an empty stdin frame tells the remote process stdin is closed
+			s.writeFrame(frameStdin, nil)
+			return
+		}
+	}
+}
+
+func (s *Session) writeFrame(kind byte, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	header := make([]byte, 5)
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := s.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(payload)
+	return err
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// uploadIdleTimeout is how long an upload may go without any bytes moving
+// before it is abandoned. Large uploads are not bounded by an overall
+// timeout.
+const uploadIdleTimeout = 2 * time.Minute
+
+// longRunningIdleTimeout is how long a response may take while the server
+// works on a long operation: a remote build step like `go build` or `npm ci`
+// that prints nothing, a deploy waiting for its release command or the quiet
+// log stream of that command. Dead
+// connections are still caught by TCP keepalives.
+const longRunningIdleTimeout = 30 * time.Minute
+
+// newStreamingClient returns an http client for long running transfers. It
+// has no overall timeout, instead the connection fails once it has been
+// idle for longer than idle.
+func newStreamingClient(idle time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &idleTimeoutConn{Conn: conn, idle: idle}, nil
+		},
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{Transport: transport}
+}
+
+// newAttachClient returns an http client for connection upgrades. The
+// upgrade handshake only exists in HTTP/1.1 and Go negotiates h2 with any
+// server offering it unless the upgrade is a websocket one, so the empty
+// TLSNextProto keeps the transport on HTTP/1.1. Interactive sessions may sit
+// idle for as long as the user wants, so there is no timeout past the
+// handshake.
+func newAttachClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSNextProto:        map[string]func(string, *tls.Conn) http.RoundTripper{},
+	}
+	return &http.Client{Transport: transport}
+}
+
+// idleTimeoutConn pushes its deadline forward on every read and write.
+type idleTimeoutConn struct {
+	net.Conn
+	idle time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetDeadline(time.Now().Add(c.idle)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	if err := c.Conn.SetDeadline(time.Now().Add(c.idle)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
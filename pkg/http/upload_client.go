@@ -0,0 +1,334 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/types"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// chunkSize is the size of every part of a resumable upload but the last.
+	chunkSize       = 8 << 20
+	maxPartAttempts = 3
+	uploadsDirName  = "uploads"
+	// uploadRetention is how long staged files of unfinished uploads are
+	// kept around for resuming.
+	uploadRetention = 7 * 24 * time.Hour
+)
+
+const (
+	UploadKindBinary = "binary"
+	UploadKindSource = "source"
+)
+
+// ErrUploadInterrupted wraps transient upload failures, the upload can be
+// resumed by running the command again.
+var ErrUploadInterrupted = errors.New("upload interrupted")
+
+// partError is a part upload rejected by the server.
+type partError struct {
+	part int
+	code int
+}
+
+func (e *partError) Error() string {
+	return fmt.Sprintf("upload of part %d failed. server returned http code %d", e.part, e.code)
+}
+
+// isTransientUploadError reports whether retrying could make err go away.
+// Network errors are, rejections like 401 or 413 are not.
+func isTransientUploadError(err error) bool {
+	var pe *partError
+	if !errors.As(err, &pe) {
+		return true
+	}
+	return pe.code >= 500 || pe.code == http.StatusRequestTimeout || pe.code == http.StatusTooManyRequests
+}
+
+// UploadClient sends large artifacts to the server in chunks. The state of an
+// upload is kept on disk next to the artifact, so an interrupted upload picks
+// up from the last chunk the server received, even from a later process.
+type UploadClient struct {
+	client     Client
+	httpClient *http.Client
+	account    *types.Account
+	appName    string
+}
+
+func NewUploadClient(appName string, account *types.Account) *UploadClient {
+	return &UploadClient{
+		client:     NewHttpClient(account),
+		httpClient: newStreamingClient(uploadIdleTimeout),
+		account:    account,
+		appName:    appName,
+	}
+}
+
+type uploadState struct {
+	Id        string `json:"id"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+}
+
+// UploadPath returns where an artifact with the given checksum is staged
+// before it is uploaded. Staging artifacts under a name derived from their
+// content is what lets an identical artifact resume an earlier upload.
+func UploadPath(checksum, ext string) (string, error) {
+	dir, err := auth.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, uploadsDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, checksum+ext), nil
+}
+
+// Upload sends the file at path and returns the id of the completed upload.
+// checksum is the SHA-256 of the file, verified by the server once every
+// chunk has arrived. The file and its upload state are removed on success.
+func (u *UploadClient) Upload(path, kind, checksum string, progress io.Writer) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	statePath := path + ".upload.json"
+	state, received := u.resume(statePath, info.Size())
+	if state == nil {
+		if state, err = u.initiate(kind, info.Size(), checksum); err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(statePath, data, 0600); err != nil {
+			return "", err
+		}
+		received = map[int]bool{}
+	}
+
+	parts := int((info.Size() + state.ChunkSize - 1) / state.ChunkSize)
+	p := newProgressReader(nil, progress, "uploading", info.Size())
+	for part := 1; part <= parts; part++ {
+		offset := int64(part-1) * state.ChunkSize
+		size := state.ChunkSize
+		if offset+size > info.Size() {
+			size = info.Size() - offset
+		}
+		if received[part] {
+			p.read += size
+			continue
+		}
+		section := io.NewSectionReader(f, offset, size)
+		if err := u.uploadPartWithRetry(state.Id, part, section, p); err != nil {
+			if isTransientUploadError(err) {
+				return "", fmt.Errorf("%w: %v. Run the command again to resume it", ErrUploadInterrupted, err)
+			}
+			// the server will not take this upload, there is nothing to resume
+			os.Remove(statePath)
+			os.Remove(path)
+			return "", err
+		}
+	}
+	p.render()
+
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	err = u.client.Do(fmt.Sprintf("/uploads/%s/complete", state.Id), "POST", nil, &serverResponse{})
+	if err != nil {
+		return "", err
+	}
+	os.Remove(statePath)
+	os.Remove(path)
+	return state.Id, nil
+}
+
+// resume loads the state of an earlier attempt to upload the file at path,
+// along with the parts the server already has. It returns a nil state when
+// there is nothing to resume.
+func (u *UploadClient) resume(statePath string, size int64) (*uploadState, map[int]bool) {
+	data, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		return nil, nil
+	}
+	state := &uploadState{}
+	if err := json.Unmarshal(data, state); err != nil || state.Size != size || state.ChunkSize <= 0 {
+		os.Remove(statePath)
+		return nil, nil
+	}
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+		Data    struct {
+			Parts []int `json:"parts"`
+		} `json:"data"`
+	}
+	s := &serverResponse{}
+	if err := u.client.Do(fmt.Sprintf("/uploads/%s", state.Id), "GET", nil, s); err != nil {
+		// the server forgot about the upload, most likely it expired
+		os.Remove(statePath)
+		return nil, nil
+	}
+	received := make(map[int]bool, len(s.Data.Parts))
+	for _, part := range s.Data.Parts {
+		received[part] = true
+	}
+	return state, received
+}
+
+func (u *UploadClient) initiate(kind string, size int64, checksum string) (*uploadState, error) {
+	type payload struct {
+		AppName   string `json:"app_name"`
+		Kind      string `json:"kind"`
+		Size      int64  `json:"size"`
+		Sha256    string `json:"sha256"`
+		ChunkSize int64  `json:"chunk_size"`
+	}
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+		Data    struct {
+			Id        string `json:"id"`
+			ChunkSize int64  `json:"chunk_size"`
+		} `json:"data"`
+	}
+	p := &payload{AppName: u.appName, Kind: kind, Size: size, Sha256: checksum, ChunkSize: chunkSize}
+	s := &serverResponse{}
+	if err := u.client.Do("/uploads", "POST", p, s); err != nil {
+		return nil, err
+	}
+	state := &uploadState{Id: s.Data.Id, Size: size, ChunkSize: s.Data.ChunkSize}
+	// the server may impose its own chunk size
+	if state.ChunkSize <= 0 {
+		state.ChunkSize = chunkSize
+	}
+	return state, nil
+}
+
+func (u *UploadClient) uploadPartWithRetry(id string, part int, section *io.SectionReader, p *progressReader) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, section); err != nil {
+		return err
+	}
+	checksum := hex.EncodeToString(h.Sum(nil))
+	start := p.read
+	var err error
+	for attempt := 1; attempt <= maxPartAttempts; attempt++ {
+		if _, err = section.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		p.read = start
+		p.Reader = section
+		if err = u.uploadPart(id, part, checksum, section.Size(), p); err == nil || !isTransientUploadError(err) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	return err
+}
+
+func (u *UploadClient) uploadPart(id string, part int, checksum string, size int64, body io.Reader) error {
+	serverUrl := fmt.Sprintf("%s/uploads/%s/parts/%d", serverUrl, id, part)
+	req, err := http.NewRequest("PUT", serverUrl, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if u.account != nil {
+		req.Header.Set("X-Auth-Token", u.account.Token)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Content-Sha256", checksum)
+	r, err := u.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return &partError{part: part, code: r.StatusCode}
+	}
+	return nil
+}
+
+// stageUpload compresses src into the uploads directory and returns the path
+// of the staged file with its SHA-256. Compressing the same content again
+// yields the same file, so an interrupted upload of it can be resumed.
+func stageUpload(src io.Reader, compression string) (string, string, error) {
+	pruneUploads()
+	dir, err := auth.ConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	tmp, err := ioutil.TempFile(dir, "staging-")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	cw, err := compressWriter(io.MultiWriter(tmp, h), compression)
+	if err != nil {
+		tmp.Close()
+		return "", "", err
+	}
+	if _, err := io.Copy(cw, src); err != nil {
+		tmp.Close()
+		return "", "", err
+	}
+	if err := cw.Close(); err != nil {
+		tmp.Close()
+		return "", "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", "", err
+	}
+	checksum := hex.EncodeToString(h.Sum(nil))
+	path, err := UploadPath(checksum, "."+compressionExtension(compression))
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", "", err
+	}
+	return path, checksum, nil
+}
+
+// pruneUploads removes staged files and upload state older than
+// uploadRetention, left behind by uploads that were never resumed.
+func pruneUploads() {
+	dir, err := auth.ConfigDir()
+	if err != nil {
+		return
+	}
+	dir = filepath.Join(dir, uploadsDirName)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if time.Since(entry.ModTime()) > uploadRetention {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
@@ -1,11 +1,19 @@
 package ops
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/saas/hostgo/pkg/http"
 	"github.com/saas/hostgo/pkg/types"
+	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type AppsOp struct {
@@ -69,7 +77,10 @@ func (op *AppsOp) RollbackDeployment(appName, version string) (string, error) {
 	return fmt.Sprintf("%s | %s", s.Message, s.Data.Version), nil
 }
 
-func (op *AppsOp) ProvisionResource(appName, resName string) (string, error) {
+// ProvisionResource adds resName, e.g postgres, to an app. Plan and version
+// are optional and default to the smallest plan and latest version listed by
+// ListResourcePlans.
+func (op *AppsOp) ProvisionResource(appName, resName, plan, version string) (string, error) {
 	type serverResponse struct {
 		Error   bool   `json:"error"`
 		Message string `json:"message"`
@@ -78,7 +89,15 @@ func (op *AppsOp) ProvisionResource(appName, resName string) (string, error) {
 		} `json:"data"`
 	}
 	s := &serverResponse{}
-	err := op.httpClient.Do(fmt.Sprintf("/apps/resource/new/%s?name=%s", appName, resName), "POST", nil, s)
+	q := url.Values{}
+	q.Set("name", resName)
+	if plan != "" {
+		q.Set("plan", plan)
+	}
+	if version != "" {
+		q.Set("version", version)
+	}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/resource/new/%s?%s", appName, q.Encode()), "POST", nil, s)
 	if err != nil {
 		return "", err
 	}
@@ -98,7 +117,9 @@ func (op *AppsOp) DeleteResource(appName, resName string) (string, error) {
 	return s.Message, nil
 }
 
-func (op *AppsOp) DockerDeploy(appName, dockerUrl string) (string, error) {
+// DockerDeploy deploys dockerUrl with settings. When settings has a release
+// phase its command is run before the new deployment receives traffic.
+func (op *AppsOp) DockerDeploy(appName, dockerUrl string, settings *types.DeploySettings) (string, error) {
 	type serverResponse struct {
 		Error   bool   `json:"error"`
 		Message string `json:"message"`
@@ -110,10 +131,11 @@ func (op *AppsOp) DockerDeploy(appName, dockerUrl string) (string, error) {
 	type payload struct {
 		AppName string `json:"app_name"`
 		DockerUrl string `json:"docker_url"`
+		*types.DeploySettings
 	}
-	p := &payload{AppName: appName, DockerUrl: dockerUrl}
+	p := &payload{AppName: appName, DockerUrl: dockerUrl, DeploySettings: settings}
 	s := &serverResponse{}
-	err := op.httpClient.Do("/apps/docker/deploy", "POST", p, s)
+	err := op.httpClient.DoLongRunning("/apps/docker/deploy", "POST", p, s)
 	if err != nil {
 		return "", err
 	}
@@ -169,4 +191,650 @@ func (op *AppsOp) DumpDatabase(appName, resName string) (string, error) {
 		return "", err
 	}
 	return fmt.Sprintf("database successfully dumped to %s.", destination), nil
-}
\ No newline at end of file
+}
+
+// ResolveImage asks the platform to look up the manifest digest of image,
+// using the app's pull secrets for private registries.
+func (op *AppsOp) ResolveImage(appName, image string) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+		Data    struct {
+			Digest string `json:"digest"`
+		} `json:"data"`
+	}
+	type payload struct {
+		AppName string `json:"app_name"`
+		Image   string `json:"image"`
+	}
+	p := &payload{AppName: appName, Image: image}
+	s := &serverResponse{}
+	err := op.httpClient.Do("/apps/docker/resolve", "POST", p, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Data.Digest, nil
+}
+
+func (op *AppsOp) AddPullSecret(appName string, secret *types.PullSecret) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/registry-credentials/%s", appName), "POST", secret, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+func (op *AppsOp) ListPullSecrets(appName string) ([]types.PullSecret, error) {
+	type serverResponse struct {
+		Error   bool               `json:"error"`
+		Message string             `json:"message"`
+		Data    []types.PullSecret `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/registry-credentials/%s", appName), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+func (op *AppsOp) RemovePullSecret(appName, registry string) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/registry-credentials/%s?registry=%s", appName, url.QueryEscape(registry)), "DELETE", nil, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+// StreamReleaseLogs copies the output of a release phase to w as it runs and
+// returns the exit code of the release command. The platform only starts the
+// release once the deployment request reaches it, so the log stream is
+// retried until it opens or stop is closed.
+func (op *AppsOp) StreamReleaseLogs(appName, releaseId string, w io.Writer, stop <-chan struct{}) (int, error) {
+	type releaseMessage struct {
+		Stream   string `json:"stream"`
+		Done     bool   `json:"done"`
+		ExitCode int    `json:"exit_code"`
+	}
+	for {
+		body, err := op.httpClient.Stream(fmt.Sprintf("/apps/releases/%s/%s/logs", appName, releaseId), "GET", nil)
+		if err != nil {
+			select {
+			case <-stop:
+				return 0, err
+			case <-time.After(time.Second):
+				continue
+			}
+		}
+		defer body.Close()
+		dec := json.NewDecoder(body)
+		for {
+			m := &releaseMessage{}
+			if err := dec.Decode(m); err != nil {
+				return 0, fmt.Errorf("release log stream ended unexpectedly: %v", err)
+			}
+			if m.Stream != "" {
+				fmt.Fprint(w, m.Stream)
+			}
+			if m.Done {
+				return m.ExitCode, nil
+			}
+		}
+	}
+}
+
+// Run starts a one-off instance of the current release of an app running
+// req.Command, and attaches to it.
+func (op *AppsOp) Run(appName string, req *types.ProcessRequest) (*http.Session, error) {
+	return op.httpClient.Attach(fmt.Sprintf("/apps/run/%s", appName), req)
+}
+
+// Scale sets the number of instances an app runs. It returns once the
+// platform accepted the change, use WaitForInstances to wait for the new
+// instances to become ready.
+func (op *AppsOp) Scale(appName string, req *types.ScaleRequest) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/scale/%s", appName), "PUT", req, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+func (op *AppsOp) ListInstances(appName string) ([]types.Instance, error) {
+	type serverResponse struct {
+		Error   bool             `json:"error"`
+		Message string           `json:"message"`
+		Data    []types.Instance `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/ps/%s", appName), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+// Exec starts req.Command in a running instance of an app and attaches to it.
+func (op *AppsOp) Exec(appName, instanceId string, req *types.ProcessRequest) (*http.Session, error) {
+	return op.httpClient.Attach(fmt.Sprintf("/apps/exec/%s/%s", appName, instanceId), req)
+}
+
+// InstanceStatusRunning is the status of an instance that is up and passing
+// its health checks.
+const InstanceStatusRunning = "running"
+
+// IsRunning reports whether instance has InstanceStatusRunning, in any case.
+func IsRunning(instance types.Instance) bool {
+	return strings.EqualFold(instance.Status, InstanceStatusRunning)
+}
+
+var ErrWaitTimeout = errors.New("timed out waiting for instances to become ready")
+
+// WaitForInstances polls the instances of an app until ready accepts them or
+// timeout elapses, and returns the last instances seen.
+func (op *AppsOp) WaitForInstances(appName string, timeout time.Duration, ready func([]types.Instance) bool) ([]types.Instance, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		instances, err := op.ListInstances(appName)
+		if err == nil && ready(instances) {
+			return instances, nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return nil, err
+			}
+			return instances, ErrWaitTimeout
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// Restart restarts a single instance of an app, or all of them when
+// instanceId is empty. A rolling restart replaces instances one at a time so
+// the app keeps serving traffic.
+func (op *AppsOp) Restart(appName, instanceId string, rolling bool) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	type payload struct {
+		InstanceId string `json:"instance_id,omitempty"`
+		Rolling    bool   `json:"rolling"`
+	}
+	p := &payload{InstanceId: instanceId, Rolling: rolling}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/restart/%s", appName), "POST", p, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+// Stop scales an app to zero instances. The platform remembers the instance
+// count so Start can restore it.
+func (op *AppsOp) Stop(appName string) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/stop/%s", appName), "POST", nil, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+// Start restores the instance count an app had before it was stopped, and
+// returns that count.
+func (op *AppsOp) Start(appName string) (int, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+		Data    struct {
+			Instances int `json:"instances"`
+		} `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/start/%s", appName), "POST", nil, s)
+	if err != nil {
+		return 0, err
+	}
+	return s.Data.Instances, nil
+}
+
+func (op *AppsOp) SetAutoscale(appName string, policy *types.AutoscalePolicy) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/autoscale/%s", appName), "PUT", policy, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+func (op *AppsOp) GetAutoscale(appName string) (*types.AutoscaleStatus, error) {
+	type serverResponse struct {
+		Error   bool                   `json:"error"`
+		Message string                 `json:"message"`
+		Data    *types.AutoscaleStatus `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/autoscale/%s", appName), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Data == nil {
+		return &types.AutoscaleStatus{}, nil
+	}
+	return s.Data, nil
+}
+
+// DisableAutoscale turns autoscaling off, leaving the app at its current
+// instance count.
+func (op *AppsOp) DisableAutoscale(appName string) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/autoscale/%s", appName), "DELETE", nil, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+// ValidateAutoscalePolicy checks a policy before it is sent to the platform.
+func ValidateAutoscalePolicy(policy *types.AutoscalePolicy) error {
+	if policy.Min < 1 {
+		return errors.New("min instances should be at least 1")
+	}
+	if policy.Max < policy.Min {
+		return fmt.Errorf("max instances (%d) should not be less than min instances (%d)", policy.Max, policy.Min)
+	}
+	if policy.TargetCpu < 0 || policy.TargetCpu > 100 {
+		return fmt.Errorf("cpu target should be a percentage between 1 and 100, got %d", policy.TargetCpu)
+	}
+	if policy.TargetRps < 0 {
+		return fmt.Errorf("rps target should be positive, got %d", policy.TargetRps)
+	}
+	if policy.TargetCpu == 0 && policy.TargetRps == 0 {
+		return errors.New("set a cpu or rps target to scale on")
+	}
+	return nil
+}
+
+func (op *AppsOp) ListSizes() ([]types.InstanceSize, error) {
+	type serverResponse struct {
+		Error   bool                 `json:"error"`
+		Message string               `json:"message"`
+		Data    []types.InstanceSize `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do("/sizes", "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+var memoryPattern = regexp.MustCompile(`^([0-9]+)(Mi|Gi)$`)
+
+// MemoryBytes converts a memory limit like 512Mi or 2Gi to bytes.
+func MemoryBytes(memory string) (int64, error) {
+	m := memoryPattern.FindStringSubmatch(memory)
+	if m == nil {
+		return 0, fmt.Errorf("invalid memory %s. expected a value like 512Mi or 2Gi", memory)
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if m[2] == "Gi" {
+		return n << 30, nil
+	}
+	return n << 20, nil
+}
+
+// ValidateScaleRequest checks the resource limits of a scale request.
+func ValidateScaleRequest(req *types.ScaleRequest) error {
+	if req.Memory != "" && !memoryPattern.MatchString(req.Memory) {
+		return fmt.Errorf("invalid memory %s. expected a value like 512Mi or 2Gi", req.Memory)
+	}
+	if req.Cpu < 0 {
+		return fmt.Errorf("invalid cpu %v. expected a positive number of cores like 0.5", req.Cpu)
+	}
+	if req.Size != "" && (req.Memory != "" || req.Cpu != 0) {
+		return errors.New("use either --size or --memory/--cpu, not both")
+	}
+	if req.Instances > 0 && len(req.Processes) > 0 {
+		return errors.New("use either -i or {process}={instances}, not both")
+	}
+	return nil
+}
+
+// DefaultProcessType is the process type of apps that do not declare any.
+const DefaultProcessType = "web"
+
+var processNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// ValidateProcesses checks the process types declared in the app config.
+func ValidateProcesses(processes map[string]types.ProcessType) error {
+	for name, process := range processes {
+		if !processNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid process type %s. names are lowercase letters, digits and dashes", name)
+		}
+		if strings.TrimSpace(process.Command) == "" {
+			return fmt.Errorf("process type %s has no command", name)
+		}
+	}
+	return nil
+}
+
+// ParseProcessCounts parses scale arguments of the form web=3 worker=2.
+func ParseProcessCounts(args []string) (map[string]int32, error) {
+	counts := make(map[string]int32, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || !processNamePattern.MatchString(parts[0]) {
+			return nil, fmt.Errorf("invalid argument %s. expected {process}={instances}, e.g web=3", arg)
+		}
+		n, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid instance count %s for process %s", parts[1], parts[0])
+		}
+		counts[parts[0]] = int32(n)
+	}
+	return counts, nil
+}
+
+func (op *AppsOp) AddCronJob(appName string, job *types.CronJob) (*types.CronJob, error) {
+	type serverResponse struct {
+		Error   bool          `json:"error"`
+		Message string        `json:"message"`
+		Data    types.CronJob `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/cron/%s", appName), "POST", job, s)
+	if err != nil {
+		return nil, err
+	}
+	return &s.Data, nil
+}
+
+func (op *AppsOp) ListCronJobs(appName string) ([]types.CronJob, error) {
+	type serverResponse struct {
+		Error   bool            `json:"error"`
+		Message string          `json:"message"`
+		Data    []types.CronJob `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/cron/%s", appName), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+func (op *AppsOp) RemoveCronJob(appName, jobId string) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/cron/%s/%s", appName, jobId), "DELETE", nil, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+// RunCronJob triggers a cron job outside of its schedule.
+func (op *AppsOp) RunCronJob(appName, jobId string) (*types.CronRun, error) {
+	type serverResponse struct {
+		Error   bool          `json:"error"`
+		Message string        `json:"message"`
+		Data    types.CronRun `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/cron/%s/%s/run", appName, jobId), "POST", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return &s.Data, nil
+}
+
+// CronHistory returns the most recent runs of a cron job, newest first.
+func (op *AppsOp) CronHistory(appName, jobId string, limit int) ([]types.CronRun, error) {
+	type serverResponse struct {
+		Error   bool            `json:"error"`
+		Message string          `json:"message"`
+		Data    []types.CronRun `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/cron/%s/%s/history?limit=%d", appName, jobId, limit), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+// Metrics returns the metrics of an app over the last since, aggregated into
+// windows of step.
+func (op *AppsOp) Metrics(appName string, since, step time.Duration) (*types.AppMetrics, error) {
+	type serverResponse struct {
+		Error   bool             `json:"error"`
+		Message string           `json:"message"`
+		Data    types.AppMetrics `json:"data"`
+	}
+	s := &serverResponse{}
+	q := url.Values{}
+	q.Set("since", strconv.FormatInt(int64(since.Seconds()), 10))
+	q.Set("step", strconv.FormatInt(int64(step.Seconds()), 10))
+	err := op.httpClient.Do(fmt.Sprintf("/apps/metrics/%s?%s", appName, q.Encode()), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return &s.Data, nil
+}
+
+// SetHealthCheck sets the health check new instances of an app have to pass
+// before they receive traffic. Defaults are filled in for unset values.
+func (op *AppsOp) SetHealthCheck(appName string, check *types.HealthCheck) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/health/%s", appName), "PUT", WithHealthCheckDefaults(check), s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+// GetHealth returns the health check of an app and the health of each of its
+// instances, including their recent failures.
+func (op *AppsOp) GetHealth(appName string) (*types.HealthStatus, error) {
+	type serverResponse struct {
+		Error   bool               `json:"error"`
+		Message string             `json:"message"`
+		Data    types.HealthStatus `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/health/%s", appName), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return &s.Data, nil
+}
+
+// WithHealthCheckDefaults returns a copy of check with unset values defaulted.
+// The port defaults to the port the platform routes traffic to.
+func WithHealthCheckDefaults(check *types.HealthCheck) *types.HealthCheck {
+	c := *check
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.Interval == 0 {
+		c.Interval = 10
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5
+	}
+	if c.HealthyThreshold == 0 {
+		c.HealthyThreshold = 2
+	}
+	if c.UnhealthyThreshold == 0 {
+		c.UnhealthyThreshold = 3
+	}
+	return &c
+}
+
+func ValidateHealthCheck(check *types.HealthCheck) error {
+	if check.Path != "" && !strings.HasPrefix(check.Path, "/") {
+		return fmt.Errorf("health check path should start with /, got %s", check.Path)
+	}
+	if check.Port < 0 || check.Port > 65535 {
+		return fmt.Errorf("invalid health check port %d", check.Port)
+	}
+	if check.Interval < 0 || check.Timeout < 0 || check.HealthyThreshold < 0 || check.UnhealthyThreshold < 0 {
+		return errors.New("health check interval, timeout and thresholds should be positive")
+	}
+	c := WithHealthCheckDefaults(check)
+	if c.Timeout >= c.Interval {
+		return fmt.Errorf("health check timeout (%ds) should be less than the interval (%ds)", c.Timeout, c.Interval)
+	}
+	return nil
+}
+
+func (op *AppsOp) ListResources(appName string) ([]types.Resource, error) {
+	type serverResponse struct {
+		Error   bool             `json:"error"`
+		Message string           `json:"message"`
+		Data    []types.Resource `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/resource/list/%s", appName), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+// ListResourcePlans returns the plans resources can be provisioned with.
+// An empty resType lists the plans of every type.
+func (op *AppsOp) ListResourcePlans(resType string) ([]types.ResourcePlan, error) {
+	type serverResponse struct {
+		Error   bool                 `json:"error"`
+		Message string               `json:"message"`
+		Data    []types.ResourcePlan `json:"data"`
+	}
+	s := &serverResponse{}
+	endpoint := "/resources/plans"
+	if resType != "" {
+		endpoint += "?type=" + url.QueryEscape(resType)
+	}
+	err := op.httpClient.Do(endpoint, "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+// ValidateResourcePlan checks that plan and version, either of which may be
+// empty, are offered for resType.
+func ValidateResourcePlan(plans []types.ResourcePlan, resType, plan, version string) error {
+	found := false
+	for _, p := range plans {
+		if p.Type != resType || (plan != "" && p.Name != plan) {
+			continue
+		}
+		found = true
+		if version == "" {
+			return nil
+		}
+		for _, v := range p.Versions {
+			if v == version {
+				return nil
+			}
+		}
+	}
+	if !found && plan != "" {
+		return fmt.Errorf("unknown %s plan %s. run `hostgo resource plans %s` to list the available plans", resType, plan, resType)
+	}
+	if !found {
+		return fmt.Errorf("unknown resource type %s. run `hostgo resource plans` to list the available resources", resType)
+	}
+	return fmt.Errorf("%s version %s is not available. run `hostgo resource plans %s` to list the available versions", resType, version, resType)
+}
+
+func (op *AppsOp) ResourceCredentials(appName, resName string) (*types.ResourceCredentials, error) {
+	type serverResponse struct {
+		Error   bool                      `json:"error"`
+		Message string                    `json:"message"`
+		Data    types.ResourceCredentials `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do(fmt.Sprintf("/apps/resource/info/%s?name=%s", appName, url.QueryEscape(resName)), "GET", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	return &s.Data, nil
+}
+
+// RotateResourceCredentials sets a new password on a resource and updates
+// the app env with it, restarting the app. It returns once the app runs with
+// the new credentials.
+func (op *AppsOp) RotateResourceCredentials(appName, resName string) (string, error) {
+	type serverResponse struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.DoLongRunning(fmt.Sprintf("/apps/resource/rotate/%s?name=%s", appName, url.QueryEscape(resName)), "POST", nil, s)
+	if err != nil {
+		return "", err
+	}
+	return s.Message, nil
+}
+
+// MaskCredentials returns a copy of c with the password masked, also in Url.
+func MaskCredentials(c *types.ResourceCredentials) *types.ResourceCredentials {
+	const mask = "********"
+	masked := *c
+	if masked.Password != "" {
+		masked.Password = mask
+	}
+	if u, err := url.Parse(c.Url); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), mask)
+			masked.Url = u.String()
+		}
+	}
+	// the password may also be carried elsewhere, e.g ?password=
+	if c.Password != "" {
+		masked.Url = strings.Replace(masked.Url, c.Password, mask, -1)
+		masked.Url = strings.Replace(masked.Url, url.QueryEscape(c.Password), mask, -1)
+	}
+	return &masked
+}
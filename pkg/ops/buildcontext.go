@@ -0,0 +1,144 @@
+package ops
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"fmt"
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/fileutils"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BuildContext packs dir into an uncompressed tar archive, leaving out the
+// files matched by its .dockerignore. It also returns the path of the
+// Dockerfile to build within the archive, empty meaning the default.
+func BuildContext(dir string, opts *BuildOptions) (io.ReadCloser, string, error) {
+	dockerfile, err := dockerfilePath(dir, opts.Dockerfile)
+	if err != nil {
+		return nil, "", err
+	}
+	excludes, err := readDockerignore(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	// like docker build, the Dockerfile and .dockerignore are always sent,
+	// even when .dockerignore matches them
+	if keep, _ := fileutils.Matches(".dockerignore", excludes); keep {
+		excludes = append(excludes, "!.dockerignore")
+	}
+	contextDockerfile := dockerfile
+	if contextDockerfile == "" {
+		contextDockerfile = "Dockerfile"
+	}
+	if keep, _ := fileutils.Matches(contextDockerfile, excludes); keep && opts.GeneratedDockerfile == nil {
+		excludes = append(excludes, "!"+contextDockerfile)
+	}
+	buildCtx, err := archive.TarWithOptions(dir, &archive.TarOptions{
+		Compression:     archive.Uncompressed,
+		ExcludePatterns: excludes,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if opts.GeneratedDockerfile != nil {
+		dockerfile = GeneratedDockerfileName
+		buildCtx = injectFile(buildCtx, GeneratedDockerfileName, opts.GeneratedDockerfile)
+	}
+	return buildCtx, dockerfile, nil
+}
+
+// ContentHash returns a short hash of the files in dir that are sent as the
+// build context, i.e not matched by its .dockerignore.
+func ContentHash(dir string) (string, error) {
+	excludes, err := readDockerignore(dir)
+	if err != nil {
+		return "", err
+	}
+	// whole directories may only be skipped when no pattern re-includes
+	// something below them
+	skipDirs := true
+	for _, pattern := range excludes {
+		if strings.HasPrefix(pattern, "!") {
+			skipDirs = false
+		}
+	}
+	h := sha256.New()
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil || name == "." {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		excluded, err := fileutils.Matches(name, excludes)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if name == ".git" || (excluded && skipDirs) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if excluded {
+			return nil
+		}
+		return hashFile(h, dir, name)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:12], nil
+}
+
+func readDockerignore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return dockerignore.ReadAll(f)
+}
+
+// dockerfilePath converts a user supplied Dockerfile path into a path relative
+// to the build context, which is what the docker daemon expects.
+func dockerfilePath(dir, dockerfile string) (string, error) {
+	if dockerfile == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(dir, dockerfile)
+	}
+	rel, err := filepath.Rel(dir, dockerfile)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("dockerfile %s must be inside the build context %s", dockerfile, dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// injectFile adds a file that only exists in memory to a build context.
+func injectFile(buildCtx io.ReadCloser, name string, content []byte) io.ReadCloser {
+	return archive.ReplaceFileTarWrapper(buildCtx, map[string]archive.TarModifierFunc{
+		name: func(_ string, _ *tar.Header, _ io.Reader) (*tar.Header, []byte, error) {
+			return &tar.Header{
+				Name:     name,
+				Mode:     0644,
+				Size:     int64(len(content)),
+				Typeflag: tar.TypeReg,
+				// a fixed time keeps the context byte for byte identical
+				// across runs, which resumable uploads rely on
+				ModTime: time.Unix(0, 0),
+			}, content, nil
+		},
+	})
+}
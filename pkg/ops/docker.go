@@ -2,53 +2,112 @@ package ops
 
 import (
 	"context"
-	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
-	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/jsonmessage"
-	"io"
+	hgtypes "github.com/saas/hostgo/pkg/types"
+	"regexp"
+	"strings"
 	"time"
 )
 
-const registryUrl = "registry.csail.app/"
+// DefaultRegistry is the hostgo registry images are pushed to unless the app
+// config names another one.
+const DefaultRegistry = "registry.csail.app"
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// BuildOptions controls how an app image is built. It mirrors the `build`
+// section of csail.yml, with command line flags taking precedence.
+type BuildOptions struct {
+	// Registry is the registry the image is pushed to, DefaultRegistry when empty.
+	Registry string
+	// Tag is the image tag to build, see ImageTag.
+	Tag        string
+	Dockerfile string
+	BuildArgs  map[string]string
+	Target     string
+	NoCache    bool
+	Platform   string
+	Labels     map[string]string
+	// GeneratedDockerfile, when set, is added to the build context in memory
+	// and used instead of Dockerfile.
+	GeneratedDockerfile []byte
+}
 
 type DockerService interface {
-	BuildImage(buildDir, appName string) (string, error)
+	BuildImage(buildDir, appName string, opts *BuildOptions) (string, error)
 	PushImage(ref string) error
+	ImageExists(ref string) bool
 }
 
 type DockerOps struct {
-	client *client.Client
+	client      *client.Client
+	credentials CredentialsSource
 }
 
-func NewDockerOps() (DockerService, error) {
+func NewDockerOps(credentials CredentialsSource) (DockerService, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
 	}
-	return &DockerOps{client: cli}, nil
+	return &DockerOps{client: cli, credentials: credentials}, nil
+}
+
+// DockerAvailable reports whether a docker daemon is reachable with the
+// current environment.
+func DockerAvailable() bool {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return false
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err = cli.Ping(ctx)
+	return err == nil
 }
 
-func (op *DockerOps) BuildImage(dir, appName string) (string, error) {
-	buildCtx, err := op.createBuildContext(dir)
+func (op *DockerOps) BuildImage(dir, appName string, opts *BuildOptions) (string, error) {
+	if opts == nil {
+		opts = &BuildOptions{}
+	}
+	var err error
+	if opts.Tag == "" {
+		if opts.Tag, err = ImageTag(dir); err != nil {
+			return "", err
+		}
+	}
+	buildCtx, dockerfile, err := BuildContext(dir, opts)
 	if err != nil {
 		return "", err
 	}
-	tag := op.randomMd5()[:6]
-	pushUrl := fmt.Sprintf("%s%s:%s", registryUrl, appName, tag)
+	defer buildCtx.Close()
+	pushUrl := ImageRef(opts.Registry, appName, opts.Tag)
+	buildArgs := make(map[string]*string, len(opts.BuildArgs))
+	for k, v := range opts.BuildArgs {
+		value := v
+		buildArgs[k] = &value
+	}
 	r, err := op.client.ImageBuild(context.Background(),
 		buildCtx, types.ImageBuildOptions{
-			NoCache: false,
-			Tags: []string{pushUrl},
+			NoCache:    opts.NoCache,
+			Tags:       []string{pushUrl},
+			Dockerfile: dockerfile,
+			BuildArgs:  buildArgs,
+			Target:     opts.Target,
+			Platform:   opts.Platform,
+			Labels:     opts.Labels,
 		})
 	if err != nil {
 		return "", err
 	}
+	defer r.Body.Close()
 	jm := jsonmessage.JSONMessage{}
 	dec := json.NewDecoder(r.Body)
 	for {
@@ -62,12 +121,31 @@ func (op *DockerOps) BuildImage(dir, appName string) (string, error) {
 	return pushUrl, nil
 }
 
+// PushImage pushes ref to the registry. Hostgo registry credentials rejected
+// by the registry are refreshed once before giving up.
 func (op *DockerOps) PushImage(ref string) error {
+	err := op.pushImage(ref, false)
+	if err != nil && isUnauthorized(err) && registryHost(ref) == DefaultRegistry {
+		err = op.pushImage(ref, true)
+	}
+	return err
+}
+
+func (op *DockerOps) pushImage(ref string, refreshAuth bool) error {
+	registryAuth, err := op.registryAuthAsBase64(ref, refreshAuth)
+	if err == ErrNoDockerCredentials {
+		host := registryHost(ref)
+		return fmt.Errorf("no credentials for %s. run `docker login %s` first", host, host)
+	}
+	if err != nil {
+		return err
+	}
 	r, err := op.client.ImagePush(context.Background(),
-		ref, types.ImagePushOptions{RegistryAuth: op.registryAuthAsBase64()})
+		ref, types.ImagePushOptions{RegistryAuth: registryAuth})
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 	dec := json.NewDecoder(r)
 	jm := jsonmessage.JSONMessage{}
 	for {
@@ -75,30 +153,139 @@ func (op *DockerOps) PushImage(ref string) error {
 			break
 		}
 		if jm.Error != nil && jm.Error.Message != "" {
+			if jm.Error.Code == 401 {
+				return errUnauthorized
+			}
 			return errors.New(jm.Error.Message)
 		}
 	}
 	return nil
 }
 
-func (op *DockerOps) createBuildContext(dir string) (io.Reader, error) {
-	return archive.Tar(dir, archive.Uncompressed)
-}
-
-func (op *DockerOps) randomMd5() string {
-	m := md5.New()
-	m.Write([]byte(time.Now().String()))
-	return fmt.Sprintf("%x", m.Sum(nil))
+// ImageExists reports whether ref has already been pushed to the registry.
+func (op *DockerOps) ImageExists(ref string) bool {
+	registryAuth, err := op.registryAuthAsBase64(ref, false)
+	if err == ErrNoDockerCredentials {
+		// public images can be inspected anonymously
+		registryAuth, err = "", nil
+	}
+	if err != nil {
+		return false
+	}
+	_, err = op.client.DistributionInspect(context.Background(), ref, registryAuth)
+	return err == nil
 }
 
-func (op *DockerOps) registryAuthAsBase64() string {
+// registryAuthAsBase64 returns the encoded credentials for the registry ref
+// lives in. Hostgo credentials are only ever sent to DefaultRegistry, other
+// registries use the login of the docker cli.
+func (op *DockerOps) registryAuthAsBase64(ref string, refresh bool) (string, error) {
+	host := registryHost(ref)
+	var c *hgtypes.RegistryCredentials
+	var err error
+	if host == DefaultRegistry {
+		c, err = op.credentials.Credentials(refresh)
+	} else {
+		c, err = DockerCredentials(host)
+	}
+	if err != nil {
+		return "", err
+	}
 	authConfig := types.AuthConfig{
-		Username: "lekan",
-		Password: "manman",
+		Username:      c.Username,
+		Password:      c.Password,
+		ServerAddress: host,
 	}
 	encoded, err := json.Marshal(authConfig)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
+
+// registryHost returns the registry domain of ref, e.g ghcr.io.
+func registryHost(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
 	if err != nil {
 		return ""
 	}
-	return base64.StdEncoding.EncodeToString(encoded)
-}
\ No newline at end of file
+	return reference.Domain(named)
+}
+
+var errUnauthorized = errors.New("the registry rejected the push credentials")
+
+func isUnauthorized(err error) bool {
+	if err == errUnauthorized {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unauthorized") || strings.Contains(msg, "authentication required")
+}
+
+// ImageLabels returns the OCI labels attached to every image built by the cli.
+// Git related labels are left out when dir is not a git repository.
+func ImageLabels(dir, cliVersion string) map[string]string {
+	labels := map[string]string{
+		"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339),
+		"app.csail.cli.version":            cliVersion,
+	}
+	if info, err := ReadGitInfo(dir); err == nil {
+		labels["org.opencontainers.image.revision"] = info.Sha
+		labels["app.csail.git.branch"] = info.Branch
+	}
+	return labels
+}
+
+// ImageTag derives a deterministic image tag from the git commit checked out
+// in dir. Uncommitted changes add a -dirty suffix and a hash of the changes,
+// so two different dirty trees never share a tag. Outside of a git
+// repository the tag is a hash of the files of the build context.
+func ImageTag(dir string) (string, error) {
+	info, err := ReadGitInfo(dir)
+	if err != nil {
+		hash, err := ContentHash(dir)
+		if err != nil {
+			return "", err
+		}
+		return "src-" + hash, nil
+	}
+	tag := info.Sha[:12]
+	if info.Dirty {
+		hash, err := DirtyHash(dir)
+		if err != nil {
+			return "", err
+		}
+		tag = fmt.Sprintf("%s-dirty-%s", tag, hash)
+	}
+	return tag, nil
+}
+
+// ValidateTag checks that tag is a valid docker image tag.
+func ValidateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid image tag %s: tags may contain letters, digits, underscores, periods and dashes, and be at most 128 characters long", tag)
+	}
+	return nil
+}
+
+// ImageRef returns the reference an app image is pushed to. An empty registry
+// means DefaultRegistry.
+func ImageRef(registry, appName, tag string) string {
+	if registry == "" {
+		registry = DefaultRegistry
+	}
+	return fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(registry, "/"), appName, tag)
+}
+
+// PinImage returns image pinned to digest. When image already names a digest
+// it must match the one the registry serves.
+func PinImage(image, digest string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %v", image, err)
+	}
+	if canonical, ok := named.(reference.Canonical); ok && canonical.Digest().String() != digest {
+		return "", fmt.Errorf("digest mismatch for %s: registry serves %s", image, digest)
+	}
+	return fmt.Sprintf("%s@%s", named.Name(), digest), nil
+}
@@ -0,0 +1,153 @@
+package ops
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// GeneratedDockerfileName is the name given to a generated Dockerfile when it
+// is injected into a build context.
+const GeneratedDockerfileName = "Dockerfile.csail"
+
+var ErrNoGoModule = errors.New("no Dockerfile or go.mod found. Add a Dockerfile or run `go mod init` to let hostgo generate one")
+var ErrNoMainPackage = errors.New("no main package found in this module")
+
+var dockerfileTemplate = template.Must(template.New("dockerfile").Parse(`# Generated by hostgo. Edit freely, hostgo only generates a Dockerfile when none exists.
+FROM golang:{{.GoVersion}} AS build
+WORKDIR /src
+# download modules in a separate layer so it is cached across code changes
+COPY go.mod go.sum* ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 GOOS=linux go build -trimpath -ldflags="-s -w" -o /out/app {{.MainPackage}}
+
+FROM scratch
+COPY --from=build /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/
+COPY --from=build /out/app /app
+ENTRYPOINT ["/app"]
+`))
+
+// HasDockerfile reports whether dir contains a Dockerfile.
+func HasDockerfile(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "Dockerfile"))
+	return err == nil
+}
+
+// GenerateDockerfile synthesises a multi-stage Dockerfile for the Go module
+// rooted at dir. When the module has more than one main package, the one at
+// the module root wins, then cmd/{appName}, then the first in lexical order.
+func GenerateDockerfile(dir, appName string) ([]byte, error) {
+	goVersion, err := readGoVersion(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	mains, err := findMainPackages(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(mains) == 0 {
+		return nil, ErrNoMainPackage
+	}
+	main := mains[0]
+	for _, m := range mains {
+		if m == "." {
+			main = m
+			break
+		}
+		if m == "./cmd/"+appName {
+			main = m
+		}
+	}
+	buf := &bytes.Buffer{}
+	err = dockerfileTemplate.Execute(buf, struct {
+		GoVersion   string
+		MainPackage string
+	}{GoVersion: goVersion, MainPackage: main})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readGoVersion returns the go directive of a go.mod file, falling back to
+// the latest Go 1 release when the directive is absent.
+func readGoVersion(goMod string) (string, error) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNoGoModule
+		}
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "1", nil
+}
+
+// findMainPackages returns the relative paths of every main package under
+// dir, in the ./path form accepted by `go build`.
+func findMainPackages(dir string) ([]string, error) {
+	found := make(map[string]bool)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != dir && (name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), path, src, parser.PackageClauseOnly)
+		if err != nil || f.Name.Name != "main" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			found["."] = true
+		} else {
+			found["./"+filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for main packages: %v", err)
+	}
+	mains := make([]string, 0, len(found))
+	for m := range found {
+		mains = append(mains, m)
+	}
+	sort.Strings(mains)
+	return mains, nil
+}
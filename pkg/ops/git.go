@@ -0,0 +1,106 @@
+package ops
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitInfo describes the state of the git working tree an app is deployed from.
+type GitInfo struct {
+	Sha    string
+	Branch string
+	Dirty  bool
+}
+
+// ReadGitInfo inspects the git repository rooted at dir. An error is returned
+// when dir is not a git repository or git is not installed.
+func ReadGitInfo(dir string) (*GitInfo, error) {
+	sha, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	branch, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	status, err := git(dir, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return &GitInfo{Sha: sha, Branch: branch, Dirty: status != ""}, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// DirtyHash returns a short hash of the uncommitted changes in the repository
+// rooted at dir, covering both modified tracked files and untracked files.
+func DirtyHash(dir string) (string, error) {
+	h := sha256.New()
+	diff := exec.Command("git", "diff", "HEAD", "--binary")
+	diff.Dir = dir
+	out, err := diff.Output()
+	if err != nil {
+		return "", err
+	}
+	h.Write(out)
+	// -z keeps names with spaces or non-ASCII characters unquoted
+	ls := exec.Command("git", "ls-files", "-z", "--others", "--exclude-standard")
+	ls.Dir = dir
+	untracked, err := ls.Output()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range strings.Split(string(untracked), "\x00") {
+		// untracked nested repositories are listed as sub/, their content is
+		// not part of this tree
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if err := hashFile(h, dir, name); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:8], nil
+}
+
+// hashFile writes name and the content of the file at dir/name to h.
+// Symlinks are hashed by their target, other non-regular files are skipped.
+func hashFile(h io.Writer, dir, name string) error {
+	path := filepath.Join(dir, name)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		data = []byte(target)
+	case info.Mode().IsRegular():
+		if data, err = ioutil.ReadFile(path); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	h.Write([]byte(name))
+	h.Write(data)
+	return nil
+}
@@ -0,0 +1,248 @@
+package ops
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"github.com/saas/hostgo/pkg/auth"
+	"github.com/saas/hostgo/pkg/http"
+	"github.com/saas/hostgo/pkg/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const registryCacheFileName = "registry.json"
+
+// CredentialsSource hands out credentials for the hostgo registry. refresh
+// forces new credentials to be issued, e.g after the registry rejected the
+// current ones.
+type CredentialsSource interface {
+	Credentials(refresh bool) (*types.RegistryCredentials, error)
+}
+
+// RegistryOp issues short-lived registry credentials for the logged in
+// account and caches them on disk until they expire.
+type RegistryOp struct {
+	httpClient http.Client
+}
+
+func NewRegistryOp(httpClient http.Client) *RegistryOp {
+	return &RegistryOp{httpClient: httpClient}
+}
+
+func (op *RegistryOp) Credentials(refresh bool) (*types.RegistryCredentials, error) {
+	if !refresh {
+		if c, err := op.cached(); err == nil {
+			return c, nil
+		}
+	}
+	type serverResponse struct {
+		Error   bool                       `json:"error"`
+		Message string                     `json:"message"`
+		Data    *types.RegistryCredentials `json:"data"`
+	}
+	s := &serverResponse{}
+	err := op.httpClient.Do("/registry/credentials", "POST", nil, s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Data == nil {
+		return nil, fmt.Errorf("server returned no registry credentials")
+	}
+	if s.Data.Registry == "" {
+		s.Data.Registry = DefaultRegistry
+	}
+	if err := op.cache(s.Data); err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+func (op *RegistryOp) cached() (*types.RegistryCredentials, error) {
+	dir, err := auth.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, registryCacheFileName))
+	if err != nil {
+		return nil, err
+	}
+	c := &types.RegistryCredentials{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	// leave some room for a push that is about to start
+	if time.Now().Add(time.Minute).After(c.ExpiresAt) {
+		return nil, fmt.Errorf("registry credentials expired at %s", c.ExpiresAt)
+	}
+	return c, nil
+}
+
+func (op *RegistryOp) cache(c *types.RegistryCredentials) error {
+	dir, err := auth.ConfigDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, registryCacheFileName), data, 0600)
+}
+
+// StoreDockerCredentials saves c in the docker cli configuration so plain
+// `docker push` and `docker pull` work against the registry. A configured
+// credential helper is used when there is one, otherwise the credentials are
+// written to ~/.docker/config.json.
+func StoreDockerCredentials(c *types.RegistryCredentials) error {
+	configDir, configFile, config, err := readDockerConfig()
+	if err != nil {
+		return err
+	}
+	if helper := credentialHelper(config, c.Registry); helper != "" {
+		payload, err := json.Marshal(map[string]string{
+			"ServerURL": c.Registry,
+			"Username":  c.Username,
+			"Secret":    c.Password,
+		})
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("docker-credential-"+helper, "store")
+		cmd.Stdin = bytes.NewReader(payload)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("docker-credential-%s: %s", helper, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	auths := make(map[string]json.RawMessage)
+	if raw, ok := config["auths"]; ok {
+		if err := json.Unmarshal(raw, &auths); err != nil {
+			return fmt.Errorf("failed to parse auths in %s: %v", configFile, err)
+		}
+	}
+	entry, err := json.Marshal(map[string]string{
+		"auth": base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password)),
+	})
+	if err != nil {
+		return err
+	}
+	auths[c.Registry] = entry
+	if config["auths"], err = json.Marshal(auths); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configFile, data, 0600)
+}
+
+// ErrNoDockerCredentials is returned by DockerCredentials when the docker cli
+// has no login for a registry.
+var ErrNoDockerCredentials = errors.New("no docker credentials for registry")
+
+// DockerCredentials looks up the credentials the docker cli uses for
+// registry, e.g ghcr.io, from its credential helper or config.json.
+func DockerCredentials(registry string) (*types.RegistryCredentials, error) {
+	_, configFile, config, err := readDockerConfig()
+	if err != nil {
+		return nil, err
+	}
+	// docker hub logins are stored under its legacy index address
+	keys := []string{registry, "https://" + registry, "http://" + registry}
+	if registry == "docker.io" {
+		keys = append(keys, "https://index.docker.io/v1/")
+	}
+	if helper := credentialHelper(config, registry); helper != "" {
+		for _, key := range keys {
+			cmd := exec.Command("docker-credential-"+helper, "get")
+			cmd.Stdin = strings.NewReader(key)
+			out, err := cmd.Output()
+			if err != nil {
+				continue
+			}
+			var c struct {
+				Username string
+				Secret   string
+			}
+			if err := json.Unmarshal(out, &c); err != nil {
+				return nil, fmt.Errorf("docker-credential-%s: %v", helper, err)
+			}
+			return &types.RegistryCredentials{Registry: registry, Username: c.Username, Password: c.Secret}, nil
+		}
+		return nil, ErrNoDockerCredentials
+	}
+	auths := make(map[string]struct {
+		Auth string `json:"auth"`
+	})
+	if raw, ok := config["auths"]; ok {
+		if err := json.Unmarshal(raw, &auths); err != nil {
+			return nil, fmt.Errorf("failed to parse auths in %s: %v", configFile, err)
+		}
+	}
+	for _, key := range keys {
+		entry, ok := auths[key]
+		if !ok || entry.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth for %s in %s: %v", key, configFile, err)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid auth for %s in %s", key, configFile)
+		}
+		return &types.RegistryCredentials{Registry: registry, Username: parts[0], Password: parts[1]}, nil
+	}
+	return nil, ErrNoDockerCredentials
+}
+
+// readDockerConfig reads the docker cli config.json, honouring DOCKER_CONFIG.
+// A missing file yields an empty config.
+func readDockerConfig() (string, string, map[string]json.RawMessage, error) {
+	h, err := homedir.Dir()
+	if err != nil {
+		return "", "", nil, err
+	}
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		configDir = filepath.Join(h, ".docker")
+	}
+	configFile := filepath.Join(configDir, "config.json")
+	config := make(map[string]json.RawMessage)
+	if data, err := ioutil.ReadFile(configFile); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return "", "", nil, fmt.Errorf("failed to parse %s: %v", configFile, err)
+		}
+	}
+	return configDir, configFile, config, nil
+}
+
+// credentialHelper returns the docker credential helper configured for
+// registry, or "" when credentials are kept in config.json.
+func credentialHelper(config map[string]json.RawMessage, registry string) string {
+	helper := ""
+	if raw, ok := config["credsStore"]; ok {
+		_ = json.Unmarshal(raw, &helper)
+	}
+	if raw, ok := config["credHelpers"]; ok {
+		helpers := make(map[string]string)
+		if err := json.Unmarshal(raw, &helpers); err == nil && helpers[registry] != "" {
+			helper = helpers[registry]
+		}
+	}
+	return helper
+}
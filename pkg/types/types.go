@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type Account struct {
 	Id          uint   `json:"id"`
 	Name        string `json:"name"`
@@ -15,7 +17,37 @@ type App struct {
 }
 
 type Config struct {
-	AppName string `yaml:"app_name"`
+	AppName   string           `yaml:"app_name"`
+	Registry  string           `yaml:"registry,omitempty"`
+	Strategy  string           `yaml:"strategy,omitempty"`
+	Predeploy []string         `yaml:"predeploy,omitempty"`
+	Release   string           `yaml:"release,omitempty"`
+	Build     BuildConfig      `yaml:"build,omitempty"`
+	Binary    BinaryConfig     `yaml:"binary,omitempty"`
+	Autoscale *AutoscalePolicy `yaml:"autoscale,omitempty"`
+	Instance  InstanceConfig   `yaml:"instance,omitempty"`
+	// Processes are the process types built from the app, e.g a web server
+	// and a queue worker. Apps without processes run a single web process.
+	Processes   map[string]ProcessType `yaml:"processes,omitempty"`
+	HealthCheck *HealthCheck           `yaml:"health_check,omitempty"`
+}
+
+type BinaryConfig struct {
+	Arch        string   `yaml:"arch,omitempty"`
+	Cgo         bool     `yaml:"cgo,omitempty"`
+	Trimpath    *bool    `yaml:"trimpath,omitempty"`
+	LdFlags     string   `yaml:"ldflags,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	Main        string   `yaml:"main,omitempty"`
+	Compression string   `yaml:"compression,omitempty"`
+}
+
+type BuildConfig struct {
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+	Target     string            `yaml:"target,omitempty"`
+	NoCache    bool              `yaml:"no_cache,omitempty"`
+	Platform   string            `yaml:"platform,omitempty"`
 }
 
 type DeploymentResult struct {
@@ -31,3 +63,232 @@ type Env struct {
 	Key string `json:"key"`
 	Value string `json:"value"`
 }
+
+type RegistryCredentials struct {
+	Registry  string    `json:"registry"`
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PullSecret struct {
+	Id        string    `json:"id"`
+	Registry  string    `json:"registry"`
+	Username  string    `json:"username"`
+	Password  string    `json:"password,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BuildRequest describes an image build performed by the remote build service.
+type BuildRequest struct {
+	Image      string            `json:"image"`
+	UploadId   string            `json:"upload_id"`
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	BuildArgs  map[string]string `json:"build_args,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	NoCache    bool              `json:"no_cache,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// ReleasePhase is a one-off command the platform runs with the new image and
+// the app env before a deployment receives traffic. A non-zero exit aborts
+// the deployment.
+type ReleasePhase struct {
+	Id      string `json:"id"`
+	Command string `json:"command"`
+}
+
+// DeploySettings are the parts of the app config sent along with a
+// deployment. The platform applies them together with the new release, so a
+// deploy that fails leaves the running release and its settings untouched.
+type DeploySettings struct {
+	Release     *ReleasePhase          `json:"release,omitempty"`
+	Processes   map[string]ProcessType `json:"processes,omitempty"`
+	HealthCheck *HealthCheck           `json:"health_check,omitempty"`
+	Autoscale   *AutoscalePolicy       `json:"autoscale,omitempty"`
+}
+
+// ProcessRequest describes an interactive process started on the platform.
+type ProcessRequest struct {
+	Command []string `json:"command"`
+	Tty     bool     `json:"tty"`
+	Width   uint16   `json:"width,omitempty"`
+	Height  uint16   `json:"height,omitempty"`
+}
+
+type Instance struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Started  string `json:"started"`
+	Size     string `json:"size"`
+	Process  string `json:"process"`
+	Release  string `json:"release"`
+	Restarts int    `json:"restarts"`
+	// Cpu is the cpu usage in percent of the instance size and CpuLimit the
+	// cores it may use. Memory and MemoryLimit are in bytes.
+	Cpu         float64 `json:"cpu"`
+	CpuLimit    float64 `json:"cpu_limit"`
+	Memory      int64   `json:"memory"`
+	MemoryLimit int64   `json:"memory_limit"`
+}
+
+// ProcessType is a named command run by the instances of an app.
+type ProcessType struct {
+	Command string `json:"command" yaml:"command"`
+}
+
+// ScaleRequest changes the instance count and/or the instance size of an
+// app. Processes sets the instance count per process type. Zero values leave
+// the current setting alone.
+type ScaleRequest struct {
+	Instances int32            `json:"instances,omitempty"`
+	Processes map[string]int32 `json:"processes,omitempty"`
+	Size      string           `json:"size,omitempty"`
+	Memory    string           `json:"memory,omitempty"`
+	Cpu       float64          `json:"cpu,omitempty"`
+}
+
+// InstanceSize is a plan an app instance can run on.
+type InstanceSize struct {
+	Name          string  `json:"name"`
+	Cpu           float64 `json:"cpu"`
+	Memory        string  `json:"memory"`
+	PricePerMonth float64 `json:"price_per_month"`
+}
+
+type InstanceConfig struct {
+	Size   string  `yaml:"size,omitempty"`
+	Memory string  `yaml:"memory,omitempty"`
+	Cpu    float64 `yaml:"cpu,omitempty"`
+}
+
+// AutoscalePolicy keeps the instance count of an app between Min and Max,
+// adding instances when either target is exceeded. A zero target is unused.
+type AutoscalePolicy struct {
+	Min       int32 `json:"min" yaml:"min"`
+	Max       int32 `json:"max" yaml:"max"`
+	TargetCpu int   `json:"target_cpu,omitempty" yaml:"cpu,omitempty"`
+	TargetRps int   `json:"target_rps,omitempty" yaml:"rps,omitempty"`
+}
+
+type AutoscaleStatus struct {
+	Enabled         bool            `json:"enabled"`
+	Policy          AutoscalePolicy `json:"policy"`
+	Instances       int32           `json:"instances"`
+	LastScaledAt    *time.Time      `json:"last_scaled_at"`
+	LastScaleReason string          `json:"last_scale_reason"`
+}
+
+// HealthCheck is an HTTP check the platform runs against every instance.
+// An instance receives traffic once HealthyThreshold checks in a row
+// succeeded and is replaced after UnhealthyThreshold failures in a row.
+// Interval and Timeout are in seconds.
+type HealthCheck struct {
+	Path               string `json:"path" yaml:"path"`
+	Port               int    `json:"port,omitempty" yaml:"port,omitempty"`
+	Interval           int    `json:"interval" yaml:"interval,omitempty"`
+	Timeout            int    `json:"timeout" yaml:"timeout,omitempty"`
+	HealthyThreshold   int    `json:"healthy_threshold" yaml:"healthy_threshold,omitempty"`
+	UnhealthyThreshold int    `json:"unhealthy_threshold" yaml:"unhealthy_threshold,omitempty"`
+}
+
+type HealthFailure struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error"`
+}
+
+type InstanceHealth struct {
+	InstanceId    string          `json:"instance_id"`
+	Name          string          `json:"name"`
+	State         string          `json:"state"`
+	LastCheckedAt *time.Time      `json:"last_checked_at"`
+	Failures      []HealthFailure `json:"failures"`
+}
+
+type HealthStatus struct {
+	Check     *HealthCheck     `json:"check"`
+	Instances []InstanceHealth `json:"instances"`
+}
+
+// CronJob is a command the platform runs on a schedule with the app image
+// and env.
+type CronJob struct {
+	Id        string     `json:"id"`
+	Schedule  string     `json:"schedule"`
+	Command   []string   `json:"command"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+	LastRun   *CronRun   `json:"last_run,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CronRun is a single execution of a cron job.
+type CronRun struct {
+	Id         string     `json:"id"`
+	JobId      string     `json:"job_id"`
+	Status     string     `json:"status"`
+	ExitCode   int        `json:"exit_code"`
+	Manual     bool       `json:"manual"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type MetricPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// AppMetrics are time series of an app over a window, one point per Step.
+// Latencies are in milliseconds, ErrorRate and Cpu in percent and Memory in
+// bytes.
+type AppMetrics struct {
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Step       int64         `json:"step_seconds"`
+	Rps        []MetricPoint `json:"rps"`
+	LatencyP50 []MetricPoint `json:"latency_p50"`
+	LatencyP95 []MetricPoint `json:"latency_p95"`
+	LatencyP99 []MetricPoint `json:"latency_p99"`
+	ErrorRate  []MetricPoint `json:"error_rate"`
+	Cpu        []MetricPoint `json:"cpu"`
+	Memory     []MetricPoint `json:"memory"`
+}
+
+// Resource is a backing service attached to an app, e.g a Postgres database.
+type Resource struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Plan      string    `json:"plan"`
+	Version   string    `json:"version"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ResourcePlan is a size a resource type can be provisioned with.
+type ResourcePlan struct {
+	Type           string   `json:"type"`
+	Name           string   `json:"name"`
+	Versions       []string `json:"versions"`
+	DefaultVersion string   `json:"default_version"`
+	Cpu            float64  `json:"cpu"`
+	Memory         string   `json:"memory"`
+	Storage        string   `json:"storage"`
+	Connections    int      `json:"connections"`
+	PricePerMonth  float64  `json:"price_per_month"`
+}
+
+// ResourceCredentials is how an app connects to a resource. Url is also set
+// in the app env as EnvVar.
+type ResourceCredentials struct {
+	Type     string `json:"type"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Database string `json:"database,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password"`
+	Url      string `json:"url"`
+	EnvVar   string `json:"env_var"`
+}